@@ -0,0 +1,232 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+type AdminUserRes struct {
+	ID               string   `json:"id"`
+	Username         string   `json:"username"`
+	Email            string   `json:"email"`
+	EmailVerified    bool     `json:"email_verified"`
+	TwoFactorEnabled bool     `json:"two_factor_enabled"`
+	Roles            []string `json:"roles"`
+	DisabledAt       string   `json:"disabled_at,omitempty"`
+	TaskCount        int64    `json:"task_count"`
+	CreatedAt        string   `json:"created_at"`
+	UpdatedAt        string   `json:"updated_at"`
+}
+
+type AdminUserDetailRes struct {
+	AdminUserRes
+	TaskCounts  map[string]int64 `json:"task_counts"`
+	LockedUntil string           `json:"locked_until,omitempty"`
+}
+
+func mapUserToAdminUserRes(user database.User, roles []string, taskCount int64) AdminUserRes {
+	disabledAt := ""
+	if user.DisabledAt.Valid {
+		disabledAt = user.DisabledAt.Time.Format(time.RFC3339)
+	}
+
+	return AdminUserRes{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+		Roles:            roles,
+		DisabledAt:       disabledAt,
+		TaskCount:        taskCount,
+		CreatedAt:        user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        user.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func (cfg *ApiConfig) respondWithAdminUser(c echo.Context, status int, user database.User) error {
+	counts, err := cfg.DB.GetUserTaskCounts(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt count tasks: %v", err))
+	}
+
+	res := AdminUserDetailRes{TaskCounts: map[string]int64{}}
+	total := int64(0)
+	for _, count := range counts {
+		res.TaskCounts[count.Status] = count.Count
+		total += count.Count
+	}
+
+	roles, err := cfg.userRoles(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve roles: %v", err))
+	}
+
+	res.AdminUserRes = mapUserToAdminUserRes(user, roles, total)
+
+	if wait, locked := cfg.loginBlocked(c.Request().Context(), loginScopeAccount, user.ID); locked {
+		res.LockedUntil = time.Now().UTC().Add(wait).Format(time.RFC3339)
+	}
+
+	return c.JSON(status, res)
+}
+
+func (cfg *ApiConfig) HandleAdminGetUsers(c echo.Context) error {
+	query := strings.TrimSpace(c.QueryParam("q"))
+
+	limit, err := parsePageLimit(c)
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, err.Error())
+	}
+
+	offset := int64(0)
+	if rawCursor := c.QueryParam("cursor"); rawCursor != "" {
+		cursor, err := decodeCursor(rawCursor)
+		if err != nil {
+			return respondWithError(c, http.StatusBadRequest, err.Error())
+		}
+		offset = cursor.Offset
+	}
+
+	users, err := cfg.DB.ListUsers(
+		c.Request().Context(),
+		database.ListUsersParams{Query: query, Limit: limit + 1, Offset: offset},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve users: %v", err))
+	}
+
+	page := PageRes[AdminUserRes]{Items: []AdminUserRes{}}
+	if int64(len(users)) > limit {
+		users = users[:limit]
+		page.NextCursor = encodeCursor(pageCursor{Offset: offset + limit})
+	}
+
+	userIDs := []string{}
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	roles, err := cfg.rolesForUsers(c.Request().Context(), userIDs)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve roles: %v", err))
+	}
+
+	for _, user := range users {
+		page.Items = append(page.Items, mapUserToAdminUserRes(
+			database.User{
+				ID:              user.ID,
+				CreatedAt:       user.CreatedAt,
+				UpdatedAt:       user.UpdatedAt,
+				Email:           user.Email,
+				Username:        user.Username,
+				EmailVerifiedAt: user.EmailVerifiedAt,
+				TotpEnabledAt:   user.TotpEnabledAt,
+				DisabledAt:      user.DisabledAt,
+			},
+			roles[user.ID],
+			user.TaskCount,
+		))
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (cfg *ApiConfig) HandleAdminGetUser(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	return cfg.respondWithAdminUser(c, http.StatusOK, user)
+}
+
+// HandleAdminDisableUser blocks the user from logging in and using their sessions or api keys
+// until the account is enabled again.
+func (cfg *ApiConfig) HandleAdminDisableUser(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	disabledUser, err := cfg.DB.DisableUser(
+		c.Request().Context(),
+		database.DisableUserParams{
+			DisabledAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt:  time.Now(),
+			ID:         user.ID,
+		},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return respondWithError(c, http.StatusConflict, "cant disable the last admin")
+	}
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt disable user: %v", err))
+	}
+
+	err = cfg.revokeUserSessions(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt revoke sessions")
+	}
+
+	return cfg.respondWithAdminUser(c, http.StatusOK, disabledUser)
+}
+
+func (cfg *ApiConfig) HandleAdminEnableUser(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	enabledUser, err := cfg.DB.EnableUser(
+		c.Request().Context(),
+		database.EnableUserParams{UpdatedAt: time.Now(), ID: user.ID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt enable user: %v", err))
+	}
+
+	return cfg.respondWithAdminUser(c, http.StatusOK, enabledUser)
+}
+
+type AdminRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleAdminDeleteUser(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	deleted, err := cfg.DB.DeleteUser(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt delete user: %v", err))
+	}
+	if deleted == 0 {
+		return respondWithError(c, http.StatusConflict, "cant delete the last admin")
+	}
+
+	return c.JSON(http.StatusOK, AdminRes{Message: fmt.Sprintf("user %s deleted successfully", user.ID)})
+}
+
+func (cfg *ApiConfig) HandleAdminRevokeUserSessions(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	err = cfg.revokeUserSessions(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt revoke sessions")
+	}
+
+	return c.JSON(http.StatusOK, AdminRes{Message: fmt.Sprintf("sessions of user %s revoked successfully", user.ID)})
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeTagsRead   = "tags:read"
+	ScopeTagsWrite  = "tags:write"
+	ScopeUserRead   = "user:read"
+)
+
+var apiKeyScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeTagsRead, ScopeTagsWrite, ScopeUserRead}
+
+type CreateApiKeyReq struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+}
+
+type ApiKeyRes struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+}
+
+// CreateApiKeyRes is the only response that contains the key itself.
+type CreateApiKeyRes struct {
+	ApiKeyRes
+	Key string `json:"key"`
+}
+
+func mapApiKeyToApiKeyRes(apiKey database.ApiKey) ApiKeyRes {
+	expiresAt := ""
+	if apiKey.ExpiresAt.Valid {
+		expiresAt = apiKey.ExpiresAt.Time.Format(time.RFC3339)
+	}
+
+	lastUsedAt := ""
+	if apiKey.LastUsedAt.Valid {
+		lastUsedAt = apiKey.LastUsedAt.Time.Format(time.RFC3339)
+	}
+
+	return ApiKeyRes{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     strings.Fields(apiKey.Scopes),
+		CreatedAt:  apiKey.CreatedAt.Format(time.RFC3339),
+		ExpiresAt:  expiresAt,
+		LastUsedAt: lastUsedAt,
+	}
+}
+
+func (cfg *ApiConfig) HandleCreateApiKey(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var createApiKeyReq CreateApiKeyReq
+	if err := json.Unmarshal(requestBytes, &createApiKeyReq); err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	name := strings.TrimSpace(createApiKeyReq.Name)
+	if name == "" || len(createApiKeyReq.Scopes) == 0 {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid, name and scopes are required")
+	}
+
+	scopes := []string{}
+	for _, scope := range createApiKeyReq.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return respondWithError(c, http.StatusBadRequest, fmt.Sprintf("unknown scope %q, must be one of: %s", scope, strings.Join(apiKeyScopes, ", ")))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	expiresAt := sql.NullTime{}
+	if createApiKeyReq.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, createApiKeyReq.ExpiresAt)
+		if err != nil {
+			return respondWithError(c, http.StatusBadRequest, "expires_at must be an RFC3339 timestamp")
+		}
+		if !parsed.After(time.Now()) {
+			return respondWithError(c, http.StatusBadRequest, "expires_at must be in the future")
+		}
+		expiresAt = sql.NullTime{Time: parsed.UTC(), Valid: true}
+	}
+
+	key, prefix, err := auth.GenerateApiKey()
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt generate api key")
+	}
+
+	apiKey, err := cfg.DB.CreateApiKey(
+		req.Context(),
+		database.CreateApiKeyParams{
+			ID:        uuid.NewString(),
+			UserID:    userID,
+			Name:      name,
+			Prefix:    prefix,
+			KeyHash:   auth.HashToken(key),
+			Scopes:    strings.Join(scopes, " "),
+			CreatedAt: time.Now().UTC(),
+			ExpiresAt: expiresAt,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create api key: %v", err))
+	}
+
+	return c.JSON(http.StatusCreated, CreateApiKeyRes{ApiKeyRes: mapApiKeyToApiKeyRes(apiKey), Key: key})
+}
+
+func (cfg *ApiConfig) HandleGetApiKeys(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	apiKeys, err := cfg.DB.GetUserApiKeys(c.Request().Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve api keys: %v", err))
+	}
+
+	apiKeysRes := []ApiKeyRes{}
+	for _, apiKey := range apiKeys {
+		apiKeysRes = append(apiKeysRes, mapApiKeyToApiKeyRes(apiKey))
+	}
+
+	return c.JSON(http.StatusOK, apiKeysRes)
+}
+
+type RevokeApiKeyRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleRevokeApiKey(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	id := c.Param("id")
+
+	revoked, err := cfg.DB.RevokeApiKey(
+		c.Request().Context(),
+		database.RevokeApiKeyParams{RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, ID: id, UserID: userID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt revoke api key: %v", err))
+	}
+	if revoked == 0 {
+		return respondWithError(c, http.StatusNotFound, "api key not found")
+	}
+
+	return c.JSON(http.StatusOK, RevokeApiKeyRes{Message: fmt.Sprintf("api key %s revoked successfully", id)})
+}
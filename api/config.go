@@ -1,8 +1,25 @@
 package api
 
-import "github.com/magicznykacpur/taskin-backend/internal/database"
+import (
+	"database/sql"
+
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/magicznykacpur/taskin-backend/metrics"
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+)
 
 type ApiConfig struct {
-	Port string
-	DB *database.Queries
-}
\ No newline at end of file
+	Config config.Config
+	DB     *database.Queries
+	// Conn is the database behind DB, statements that have to happen together run in a
+	// transaction on it.
+	Conn   *sql.DB
+	Mailer mail.Mailer
+	// RateLimiter holds the buckets of the rate limiting middleware, requests aren't limited without it.
+	RateLimiter ratelimit.Store
+	Health      *Health
+	// Metrics counts logins, nothing is recorded without it.
+	Metrics *metrics.Metrics
+}
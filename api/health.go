@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/reminders"
+	"github.com/magicznykacpur/taskin-backend/sql/schema"
+)
+
+const healthCheckTimeout = 2 * time.Second
+
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+	HealthDraining    = "draining"
+)
+
+// Health is what the health endpoints check, a nil dependency is left out of the report.
+type Health struct {
+	DB        *sql.DB
+	Migrator  *schema.Migrator
+	Reminders *reminders.Worker
+	Version   string
+	Commit    string
+	StartedAt time.Time
+
+	draining atomic.Bool
+}
+
+// StartDraining fails readiness from now on, so load balancers stop sending new requests
+// while the in-flight ones finish.
+func (h *Health) StartDraining() {
+	h.draining.Store(true)
+}
+
+type HealthRes struct {
+	Status        string                     `json:"status"`
+	Version       string                     `json:"version"`
+	Commit        string                     `json:"commit,omitempty"`
+	Uptime        string                     `json:"uptime"`
+	UptimeSeconds int64                      `json:"uptime_seconds"`
+	Database      *DatabaseHealthRes         `json:"database,omitempty"`
+	Migrations    *MigrationsHealthRes       `json:"migrations,omitempty"`
+	Workers       map[string]WorkerHealthRes `json:"workers"`
+}
+
+type DatabaseHealthRes struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type MigrationsHealthRes struct {
+	Status  string `json:"status"`
+	Version int64  `json:"version"`
+	Latest  int64  `json:"latest"`
+	Error   string `json:"error,omitempty"`
+}
+
+type WorkerHealthRes struct {
+	Running   bool   `json:"running"`
+	LastRunAt string `json:"last_run_at,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// checkDatabase pings the database and runs a trivial query, a ping alone can succeed on a
+// connection that can't actually run queries.
+func (h *Health) checkDatabase(ctx context.Context) *DatabaseHealthRes {
+	started := time.Now()
+
+	err := h.DB.PingContext(ctx)
+	if err == nil {
+		var one int
+		err = h.DB.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	}
+
+	res := &DatabaseHealthRes{Status: HealthOK, LatencyMS: time.Since(started).Milliseconds()}
+	if err != nil {
+		res.Status = HealthUnavailable
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// checkMigrations is only ok when the database is at exactly the latest embedded migration.
+func (h *Health) checkMigrations(ctx context.Context) *MigrationsHealthRes {
+	current, latest, err := h.Migrator.Versions(ctx)
+	if err != nil {
+		return &MigrationsHealthRes{Status: HealthUnavailable, Error: err.Error()}
+	}
+
+	res := &MigrationsHealthRes{Status: HealthOK, Version: current, Latest: latest}
+	if current < latest {
+		res.Status = HealthUnavailable
+		res.Error = "database has pending migrations"
+	}
+	if current > latest {
+		res.Status = HealthUnavailable
+		res.Error = schema.ErrDatabaseNewer.Error()
+	}
+
+	return res
+}
+
+func (h *Health) report(ctx context.Context) HealthRes {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	uptime := time.Since(h.StartedAt).Truncate(time.Second)
+	res := HealthRes{
+		Status:        HealthOK,
+		Version:       h.Version,
+		Commit:        h.Commit,
+		Uptime:        uptime.String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Workers:       map[string]WorkerHealthRes{},
+	}
+
+	if h.DB != nil {
+		res.Database = h.checkDatabase(ctx)
+		if res.Database.Status != HealthOK {
+			res.Status = HealthUnavailable
+		}
+	}
+
+	if h.Migrator != nil {
+		res.Migrations = h.checkMigrations(ctx)
+		if res.Migrations.Status != HealthOK {
+			res.Status = HealthUnavailable
+		}
+	}
+
+	if h.Reminders != nil {
+		health := h.Reminders.Health()
+		worker := WorkerHealthRes{Running: health.Running, LastError: health.LastError}
+		if !health.LastRunAt.IsZero() {
+			worker.LastRunAt = health.LastRunAt.Format(time.RFC3339)
+		}
+		res.Workers["reminders"] = worker
+	}
+
+	if h.draining.Load() {
+		res.Status = HealthDraining
+	}
+
+	return res
+}
+
+// HandleLiveness reports the same checks as readiness but always responds with 200, the process
+// is alive as long as it can answer. Restarting it wouldn't fix a database outage.
+func (cfg *ApiConfig) HandleLiveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, cfg.Health.report(c.Request().Context()))
+}
+
+// HandleReadiness responds with 503 unless every dependency is healthy and the server isn't
+// shutting down.
+func (cfg *ApiConfig) HandleReadiness(c echo.Context) error {
+	res := cfg.Health.report(c.Request().Context())
+	if res.Status != HealthOK {
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/magicznykacpur/taskin-backend/metrics"
+)
+
+// Failed logins are counted per account (subject is the user id) and per client ip.
+const (
+	loginScopeAccount = "account"
+	loginScopeIP      = "ip"
+)
+
+// loginLockout falls back to the default lockout when none is configured.
+func (cfg *ApiConfig) loginLockout() config.LoginLockout {
+	if cfg.Config.LoginLockout == (config.LoginLockout{}) {
+		return config.DefaultLoginLockout
+	}
+
+	return cfg.Config.LoginLockout
+}
+
+func loginBackoff(lockout config.LoginLockout, failures, threshold int64) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	delay := lockout.BaseDelay
+	for i := threshold; i < failures && delay < lockout.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, lockout.MaxDelay)
+}
+
+// loginBlocked returns how long the subject has to wait before it can try to log in again,
+// and whether that's because the account is locked.
+func (cfg *ApiConfig) loginBlocked(ctx context.Context, scope, subject string) (time.Duration, bool) {
+	attempt, err := cfg.DB.GetLoginAttempt(ctx, database.GetLoginAttemptParams{Scope: scope, Subject: subject})
+	if err != nil || !attempt.BlockedUntil.Valid {
+		return 0, false
+	}
+
+	wait := time.Until(attempt.BlockedUntil.Time)
+	if wait <= 0 {
+		return 0, false
+	}
+
+	return wait, attempt.LockedAt.Valid
+}
+
+// recordLoginFailure counts a failure against the subject and blocks it once it goes over
+// backoffAfter or lockAfter, it reports whether this failure locked the subject.
+func (cfg *ApiConfig) recordLoginFailure(ctx context.Context, scope, subject string, backoffAfter, lockAfter int64) (bool, error) {
+	lockout := cfg.loginLockout()
+	now := time.Now().UTC()
+
+	attempt, err := cfg.DB.GetLoginAttempt(ctx, database.GetLoginAttemptParams{Scope: scope, Subject: subject})
+	if err == nil {
+		stale := attempt.LastFailedAt.Before(now.Add(-lockout.Window))
+		lockExpired := attempt.LockedAt.Valid && !attempt.BlockedUntil.Time.After(now)
+
+		if stale || lockExpired {
+			err := cfg.DB.ClearLoginAttempts(ctx, database.ClearLoginAttemptsParams{Scope: scope, Subject: subject})
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	attempt, err = cfg.DB.RecordLoginFailure(
+		ctx,
+		database.RecordLoginFailureParams{Scope: scope, Subject: subject, LastFailedAt: now},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if lockAfter > 0 && attempt.Failures >= lockAfter {
+		err := cfg.DB.BlockLoginAttempts(ctx, database.BlockLoginAttemptsParams{
+			BlockedUntil: sql.NullTime{Time: now.Add(lockout.LockDuration), Valid: true},
+			LockedAt:     sql.NullTime{Time: now, Valid: true},
+			Scope:        scope,
+			Subject:      subject,
+		})
+
+		return err == nil && !attempt.LockedAt.Valid, err
+	}
+
+	if delay := loginBackoff(lockout, attempt.Failures, backoffAfter); delay > 0 {
+		return false, cfg.DB.BlockLoginAttempts(ctx, database.BlockLoginAttemptsParams{
+			BlockedUntil: sql.NullTime{Time: now.Add(delay), Valid: true},
+			Scope:        scope,
+			Subject:      subject,
+		})
+	}
+
+	return false, nil
+}
+
+// checkLoginAllowed responds with 429 while the client ip or the account is backing off and
+// with 423 while the account is locked, it returns false when it did so.
+func (cfg *ApiConfig) checkLoginAllowed(c echo.Context, userID string) (bool, error) {
+	if wait, _ := cfg.loginBlocked(c.Request().Context(), loginScopeIP, c.RealIP()); wait > 0 {
+		cfg.Metrics.RecordLogin(metrics.LoginBlocked)
+		return false, respondLoginBlocked(c, wait, false)
+	}
+
+	if userID == "" {
+		return true, nil
+	}
+
+	if wait, locked := cfg.loginBlocked(c.Request().Context(), loginScopeAccount, userID); wait > 0 {
+		cfg.Metrics.RecordLogin(metrics.LoginBlocked)
+		return false, respondLoginBlocked(c, wait, locked)
+	}
+
+	return true, nil
+}
+
+// recordFailedLogin counts a failed login against the client ip and, when the email belonged
+// to one, the user's account. The user is emailed when the failure locks their account.
+func (cfg *ApiConfig) recordFailedLogin(c echo.Context, user *database.User) error {
+	cfg.Metrics.RecordLogin(metrics.LoginFailure)
+
+	lockout := cfg.loginLockout()
+
+	_, err := cfg.recordLoginFailure(c.Request().Context(), loginScopeIP, c.RealIP(), lockout.IPBackoffAfter, 0)
+	if err != nil || user == nil {
+		return err
+	}
+
+	locked, err := cfg.recordLoginFailure(c.Request().Context(), loginScopeAccount, user.ID, lockout.BackoffAfter, lockout.LockAfter)
+	if err != nil || !locked {
+		return err
+	}
+
+	err = cfg.sendMail(c.Request().Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nafter %d failed login attempts your account has been locked for %d minutes.\n\nIf it wasn't you, someone might be trying to guess your password. Resetting your password unlocks the account right away.",
+			user.Username, lockout.LockAfter, int(lockout.LockDuration.Minutes()),
+		),
+	})
+	if err != nil {
+		log.Printf("couldnt send account locked email for %s: %v", user.Email, err)
+	}
+
+	return nil
+}
+
+func (cfg *ApiConfig) clearAccountLock(ctx context.Context, userID string) error {
+	return cfg.DB.ClearLoginAttempts(ctx, database.ClearLoginAttemptsParams{Scope: loginScopeAccount, Subject: userID})
+}
+
+func respondLoginBlocked(c echo.Context, retryAfter time.Duration, locked bool) error {
+	setRetryAfter(c, retryAfter)
+
+	if locked {
+		return respondWithError(c, http.StatusLocked, "account locked after too many failed login attempts, try again later")
+	}
+
+	return respondWithError(c, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+}
+
+// HandleAdminUnlockUser lifts the lock and forgets the failed logins of the user's account.
+func (cfg *ApiConfig) HandleAdminUnlockUser(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	err = cfg.clearAccountLock(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt unlock user: %v", err))
+	}
+
+	return cfg.respondWithAdminUser(c, http.StatusOK, user)
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/magicznykacpur/taskin-backend/mail"
+)
+
+// appLink builds a link into the web app, or returns just the token when no AppURL is configured.
+func (cfg *ApiConfig) appLink(path, token string) string {
+	if cfg.Config.AppURL == "" {
+		return token
+	}
+
+	return fmt.Sprintf("%s%s?token=%s", strings.TrimRight(cfg.Config.AppURL, "/"), path, token)
+}
+
+func (cfg *ApiConfig) sendMail(ctx context.Context, msg mail.Message) error {
+	if cfg.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+
+	return cfg.Mailer.Send(ctx, msg)
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
-	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -10,34 +13,126 @@ import (
 	"github.com/magicznykacpur/taskin-backend/internal/database"
 )
 
+// LoggedInMiddleware authenticates the request either with a session jwt or with an api key
+// ("Authorization: ApiKey <key>"). Requests made with an api key carry its scopes in the
+// apiKeyScopes header, RequireScope and RequireSession decide what they can access.
 func (cfg *ApiConfig) LoggedInMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		refreshToken, bearerToken, err := auth.GetAuthTokensFromHeaders(c.Request().Header)
+		header := c.Request().Header
+		header.Del("sessionID")
+		header.Del("apiKeyID")
+		header.Del("apiKeyScopes")
+
+		if strings.HasPrefix(header.Get("Authorization"), "ApiKey ") {
+			return cfg.authenticateApiKey(c, next)
+		}
+
+		bearerToken, err := auth.GetBearerToken(header)
 		if err != nil {
 			return respondWithError(c, http.StatusUnauthorized, "missing authorization")
 		}
 
-		userID, err := auth.ValidateJWTToken(bearerToken, os.Getenv("JWT_SECRET"))
-		if err != nil && err.Error() == "token has invalid claims: token is expired" {
-			dbRefreshToken, err := cfg.DB.GetValidRefreshTokenByValue(
-				c.Request().Context(),
-				database.GetValidRefreshTokenByValueParams{
-					ExpiresAt: time.Now(),
-					Token:     refreshToken,
-				},
-			)
-			if err != nil {
-				return respondWithError(c, http.StatusUnauthorized, "invalid refresh or jwt token")
+		claims, err := auth.ValidateSessionJWTToken(bearerToken, cfg.Config.JWTSecret)
+		if err != nil || claims.SessionID == "" {
+			return respondWithError(c, http.StatusUnauthorized, "invalid jwt token")
+		}
+
+		session, err := cfg.DB.GetSessionByID(c.Request().Context(), claims.SessionID)
+		if err != nil || session.UserID != claims.Subject || session.RevokedAt.Valid {
+			return respondWithError(c, http.StatusUnauthorized, "session revoked")
+		}
+
+		if time.Since(session.LastUsedAt) > sessionTouchInterval {
+			cfg.DB.TouchSession(c.Request().Context(), database.TouchSessionParams{LastUsedAt: time.Now().UTC(), ID: session.ID})
+		}
+
+		if active, err := cfg.checkUserActive(c, claims.Subject); !active {
+			return err
+		}
+
+		header.Set("userID", claims.Subject)
+		header.Set("sessionID", session.ID)
+		return next(c)
+	}
+}
+
+func (cfg *ApiConfig) authenticateApiKey(c echo.Context, next echo.HandlerFunc) error {
+	key, err := auth.GetApiKey(c.Request().Header)
+	if err != nil {
+		return respondWithError(c, http.StatusUnauthorized, "missing authorization")
+	}
+
+	apiKey, err := cfg.DB.GetApiKeyByHash(c.Request().Context(), auth.HashToken(key))
+	if err != nil || apiKey.RevokedAt.Valid {
+		return respondWithError(c, http.StatusUnauthorized, "invalid api key")
+	}
+
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return respondWithError(c, http.StatusUnauthorized, "api key expired")
+	}
+
+	if active, err := cfg.checkUserActive(c, apiKey.UserID); !active {
+		return err
+	}
+
+	if !apiKey.LastUsedAt.Valid || time.Since(apiKey.LastUsedAt.Time) > sessionTouchInterval {
+		cfg.DB.TouchApiKey(
+			c.Request().Context(),
+			database.TouchApiKeyParams{LastUsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, ID: apiKey.ID},
+		)
+	}
+
+	c.Request().Header.Set("userID", apiKey.UserID)
+	c.Request().Header.Set("apiKeyID", apiKey.ID)
+	c.Request().Header.Set("apiKeyScopes", apiKey.Scopes)
+	return next(c)
+}
+
+// checkUserActive responds with 401 when the user the credentials belong to was deleted and
+// with 403 when they're disabled, it returns false when it did so.
+func (cfg *ApiConfig) checkUserActive(c echo.Context, userID string) (bool, error) {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, respondWithError(c, http.StatusUnauthorized, "user not found")
+	}
+	if err != nil {
+		return false, respondWithError(c, http.StatusInternalServerError, "couldnt retrieve user")
+	}
+
+	if user.DisabledAt.Valid {
+		return false, respondWithError(c, http.StatusForbidden, "account disabled")
+	}
+
+	return true, nil
+}
+
+// RequireScope lets api keys through only when they were granted scope, sessions always
+// have access. It goes after LoggedInMiddleware.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get("apiKeyID") == "" {
+				return next(c)
 			}
 
-			freshJWT, err := auth.GenerateJWTToken(dbRefreshToken.UserID, os.Getenv("JWT_SECRET"), time.Hour)
-			c.SetCookie(&http.Cookie{Name: "jwt_token", Value: freshJWT, Path: "/"})
-			c.Request().Header.Set("userID", dbRefreshToken.UserID)
+			scopes := strings.Fields(c.Request().Header.Get("apiKeyScopes"))
+			if !slices.Contains(scopes, scope) {
+				return respondWithError(c, http.StatusForbidden, "api key is missing scope "+scope)
+			}
 
 			return next(c)
 		}
+	}
+}
+
+// RequireSession keeps api keys away from routes that manage the account and its credentials.
+// It goes after LoggedInMiddleware.
+func RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Header.Get("apiKeyID") != "" {
+			return respondWithError(c, http.StatusForbidden, "api keys cant access this endpoint")
+		}
 
-		c.Request().Header.Set("userID", userID)
 		return next(c)
 	}
 }
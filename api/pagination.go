@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type PageRes[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// pageCursor is serialized into the opaque cursor handed to clients.
+// Listings use the sort key of the last item, search results an offset.
+type pageCursor struct {
+	SortBy string `json:"s,omitempty"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Offset int64  `json:"o,omitempty"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("cursor malformed")
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return pageCursor{}, fmt.Errorf("cursor malformed")
+	}
+
+	return cursor, nil
+}
+
+func parsePageLimit(c echo.Context) (int64, error) {
+	raw := c.QueryParam("limit")
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("limit must be a number between 1 and %d", maxPageLimit)
+	}
+
+	return limit, nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/magicznykacpur/taskin-backend/tracing"
+)
+
+const passwordResetTokenLifetime = 30 * time.Minute
+
+// hashPassword and comparePassword run bcrypt in spans of their own, it's slow on purpose and
+// takes up most of the time of the auth routes.
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracing.Start(ctx, "bcrypt.hash")
+	defer span.End()
+
+	return auth.HashPassword(password)
+}
+
+func comparePassword(ctx context.Context, hash, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.compare")
+	defer span.End()
+
+	return auth.ComparePassword(hash, password)
+}
+
+type ForgotPasswordReq struct {
+	Email string `json:"email"`
+}
+
+type PasswordRes struct {
+	Message string `json:"message"`
+}
+
+// HandleForgotPassword responds the same way whether or not the email belongs to a user,
+// so it can't be used to find out who has an account.
+func (cfg *ApiConfig) HandleForgotPassword(c echo.Context) error {
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var forgotPasswordReq ForgotPasswordReq
+	if err := json.Unmarshal(requestBytes, &forgotPasswordReq); err != nil || forgotPasswordReq.Email == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	res := PasswordRes{Message: "if an account with that email exists, a reset link has been sent"}
+
+	user, err := cfg.DB.GetUserByEmail(req.Context(), forgotPasswordReq.Email)
+	if err != nil {
+		return c.JSON(http.StatusAccepted, res)
+	}
+
+	err = cfg.DB.InvalidatePasswordResetTokens(
+		req.Context(),
+		database.InvalidatePasswordResetTokensParams{UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, UserID: user.ID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt invalidate reset tokens")
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt generate reset token")
+	}
+
+	err = cfg.DB.CreatePasswordResetToken(req.Context(), database.CreatePasswordResetTokenParams{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(passwordResetTokenLifetime),
+	})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt create reset token")
+	}
+
+	err = cfg.sendMail(req.Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nuse the following link to reset your password, it expires in %d minutes:\n\n%s\n\nIf you didn't ask for a password reset, you can ignore this email.",
+			user.Username, int(passwordResetTokenLifetime.Minutes()), cfg.appLink("/reset-password", token),
+		),
+	})
+	// Failing here would tell the caller the account exists, the user can ask for another link.
+	if err != nil {
+		log.Printf("couldnt send reset email for %s: %v", user.Email, err)
+	}
+
+	return c.JSON(http.StatusAccepted, res)
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (cfg *ApiConfig) HandleResetPassword(c echo.Context) error {
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var resetPasswordReq ResetPasswordReq
+	if err := json.Unmarshal(requestBytes, &resetPasswordReq); err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	if resetPasswordReq.Token == "" || resetPasswordReq.Password == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	resetToken, err := cfg.DB.GetPasswordResetTokenByHash(req.Context(), auth.HashToken(resetPasswordReq.Token))
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, "invalid or expired reset token")
+	}
+
+	used, err := cfg.DB.UsePasswordResetToken(
+		req.Context(),
+		database.UsePasswordResetTokenParams{UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, ID: resetToken.ID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt use reset token")
+	}
+	if used == 0 {
+		return respondWithError(c, http.StatusBadRequest, "invalid or expired reset token")
+	}
+
+	hash, err := hashPassword(req.Context(), resetPasswordReq.Password)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudlnt hash password")
+	}
+
+	err = cfg.DB.UpdateUserPassword(
+		req.Context(),
+		database.UpdateUserPasswordParams{HashedPassword: string(hash), UpdatedAt: time.Now(), ID: resetToken.UserID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt update password")
+	}
+
+	err = cfg.revokeUserSessions(req.Context(), resetToken.UserID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt revoke sessions")
+	}
+
+	err = cfg.clearAccountLock(req.Context(), resetToken.UserID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt unlock account")
+	}
+
+	return c.JSON(http.StatusOK, PasswordRes{Message: "password reset successfully, please log in again"})
+}
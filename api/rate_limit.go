@@ -0,0 +1,58 @@
+package api
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+)
+
+// RateLimitByIP limits the requests every client ip makes to the routes of group.
+func (cfg *ApiConfig) RateLimitByIP(group string, limit ratelimit.Limit) echo.MiddlewareFunc {
+	return cfg.rateLimit(group, limit, func(c echo.Context) string {
+		return "ip:" + c.RealIP()
+	})
+}
+
+// RateLimitByUser limits the requests every user makes to the routes of group, whether with a
+// session or an api key. It goes after LoggedInMiddleware.
+func (cfg *ApiConfig) RateLimitByUser(group string, limit ratelimit.Limit) echo.MiddlewareFunc {
+	return cfg.rateLimit(group, limit, func(c echo.Context) string {
+		return "user:" + c.Request().Header.Get("userID")
+	})
+}
+
+// rateLimit takes a token from the bucket of the request's key and responds with 429 once it's
+// empty. The RateLimit-* headers tell clients how much of the limit they have left. When the
+// store fails the request is let through, an outage of the store shouldn't take the api down.
+func (cfg *ApiConfig) rateLimit(group string, limit ratelimit.Limit, key func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.RateLimiter == nil {
+				return next(c)
+			}
+
+			result, err := cfg.RateLimiter.Take(c.Request().Context(), group+":"+key(c), limit)
+			if err != nil {
+				log.Printf("couldnt check rate limit of %s: %v", group, err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Policy", limit.Policy())
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+			if !result.Allowed {
+				setRetryAfter(c, result.RetryAfter)
+				return respondWithError(c, http.StatusTooManyRequests, "rate limit exceeded, try again later")
+			}
+
+			return next(c)
+		}
+	}
+}
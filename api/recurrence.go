@@ -0,0 +1,333 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	RecurrenceScopeThis   = "this"
+	RecurrenceScopeFuture = "future"
+
+	defaultPreviewCount = 10
+	maxPreviewCount     = 100
+)
+
+// parseRecurrenceRule parses a single RRULE line (with or without the "RRULE:" prefix)
+// and anchors it at start. Rules are evaluated in UTC, the same way due dates are stored.
+func parseRecurrenceRule(rule string, start time.Time) (*rrule.RRule, error) {
+	option, err := rrule.StrToROption(strings.TrimSpace(rule))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule: %v", err)
+	}
+
+	if !option.Dtstart.IsZero() {
+		return nil, fmt.Errorf("invalid recurrence rule: DTSTART is taken from due_until")
+	}
+
+	if option.Freq == rrule.SECONDLY || option.Freq == rrule.MINUTELY {
+		return nil, fmt.Errorf("invalid recurrence rule: frequency must be hourly or less often")
+	}
+
+	if option.Count != 0 && !option.Until.IsZero() {
+		return nil, fmt.Errorf("invalid recurrence rule: COUNT and UNTIL cant both be set")
+	}
+
+	option.Dtstart = start.UTC()
+
+	return rrule.NewRRule(*option)
+}
+
+// normalizeRecurrenceRule validates rule anchored at start, the due date of the task it's set
+// on, and returns it in canonical form, without DTSTART.
+func normalizeRecurrenceRule(rule string, start time.Time) (string, error) {
+	parsed, err := parseRecurrenceRule(rule, start)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := parsed.Iterator()(); !ok {
+		return "", fmt.Errorf("invalid recurrence rule: no occurrence on or after due_until")
+	}
+
+	return parsed.OrigOptions.RRuleString(), nil
+}
+
+func (cfg *ApiConfig) createSeriesForTask(ctx context.Context, task database.Task, rule string) (database.Task, error) {
+	series, err := cfg.DB.CreateTaskSeries(
+		ctx,
+		database.CreateTaskSeriesParams{
+			ID:          uuid.NewString(),
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
+			Rule:        rule,
+			StartsAt:    task.DueUntil,
+			Title:       task.Title,
+			Description: task.Description,
+			Priority:    task.Priority,
+			UserID:      task.UserID,
+		},
+	)
+	if err != nil {
+		return database.Task{}, err
+	}
+
+	return cfg.DB.SetTaskSeries(
+		ctx,
+		database.SetTaskSeriesParams{
+			SeriesID:  sql.NullString{String: series.ID, Valid: true},
+			UpdatedAt: time.Now().UTC(),
+			ID:        task.ID,
+			UserID:    task.UserID,
+		},
+	)
+}
+
+// countToUntil replaces the COUNT of rule with the UNTIL of the last of count occurrences from
+// start. A COUNT is counted from the start of the series, it would start over every time the
+// series is re-anchored. Rules without a COUNT are returned as they are.
+func countToUntil(rule string, start time.Time, count int) (string, error) {
+	parsed, err := parseRecurrenceRule(rule, start)
+	if err != nil {
+		return "", err
+	}
+
+	option := parsed.OrigOptions
+	if option.Count == 0 {
+		return rule, nil
+	}
+
+	option.Count = count
+	limited, err := rrule.NewRRule(option)
+	if err != nil {
+		return "", err
+	}
+	occurrences := limited.All()
+	if len(occurrences) == 0 {
+		return "", fmt.Errorf("invalid recurrence rule: no occurrence on or after %s", start.UTC().Format(time.RFC3339))
+	}
+
+	option.Count = 0
+	option.Until = occurrences[len(occurrences)-1]
+
+	return option.RRuleString(), nil
+}
+
+// remainingOccurrences counts the occurrences of a series limited by COUNT that come after due.
+func remainingOccurrences(series database.TaskSeries, due time.Time) (int, error) {
+	parsed, err := parseRecurrenceRule(series.Rule, series.StartsAt)
+	if err != nil {
+		return 0, err
+	}
+	if parsed.OrigOptions.Count == 0 {
+		return 0, nil
+	}
+
+	remaining := 0
+	for _, occurrence := range parsed.All() {
+		if occurrence.After(due) {
+			remaining++
+		}
+	}
+
+	return remaining, nil
+}
+
+// updateFutureOccurrences copies the edited occurrence back into its series template, so every
+// occurrence generated from now on picks the change up. The series is re-anchored at the
+// occurrence when its due date or rule changed. A new rule's COUNT is counted from the
+// occurrence, the series' own keeps the number of occurrences it had left.
+func (cfg *ApiConfig) updateFutureOccurrences(ctx context.Context, previous, updated database.Task, rule string) error {
+	series, err := cfg.DB.GetTaskSeriesByID(ctx, updated.SeriesID.String)
+	if err != nil {
+		return err
+	}
+
+	startsAt := series.StartsAt
+	ruleChanged := rule != "" && rule != series.Rule
+	if rule == "" {
+		rule = series.Rule
+	}
+	if ruleChanged || !updated.DueUntil.Equal(previous.DueUntil) {
+		startsAt = updated.DueUntil
+
+		count := 0
+		if ruleChanged {
+			parsed, err := parseRecurrenceRule(rule, startsAt)
+			if err != nil {
+				return err
+			}
+			count = parsed.OrigOptions.Count
+		} else {
+			remaining, err := remainingOccurrences(series, previous.DueUntil)
+			if err != nil {
+				return err
+			}
+			count = remaining + 1
+		}
+
+		rule, err = countToUntil(rule, startsAt, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = cfg.DB.UpdateTaskSeriesByID(
+		ctx,
+		database.UpdateTaskSeriesByIDParams{
+			Rule:        rule,
+			StartsAt:    startsAt,
+			Title:       updated.Title,
+			Description: updated.Description,
+			Priority:    updated.Priority,
+			UpdatedAt:   time.Now().UTC(),
+			ID:          series.ID,
+			UserID:      updated.UserID,
+		},
+	)
+
+	return err
+}
+
+// scheduleNextOccurrence creates the occurrence that follows task in its series. Nothing is
+// created when the rule is exhausted or when the occurrence already exists, e.g. because the
+// task was reopened and completed again.
+func (cfg *ApiConfig) scheduleNextOccurrence(ctx context.Context, task database.Task) error {
+	if !task.SeriesID.Valid {
+		return nil
+	}
+
+	series, err := cfg.DB.GetTaskSeriesByID(ctx, task.SeriesID.String)
+	if err != nil {
+		return err
+	}
+
+	rule, err := parseRecurrenceRule(series.Rule, series.StartsAt)
+	if err != nil {
+		return err
+	}
+
+	next := rule.After(task.DueUntil, false)
+	if next.IsZero() {
+		return nil
+	}
+	next = next.UTC()
+
+	_, err = cfg.DB.GetSeriesOccurrence(ctx, database.GetSeriesOccurrenceParams{SeriesID: task.SeriesID, DueUntil: next})
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	occurrence, err := cfg.DB.CreateTask(
+		ctx,
+		database.CreateTaskParams{
+			ID:          uuid.NewString(),
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
+			DueUntil:    next,
+			Title:       series.Title,
+			Description: series.Description,
+			Priority:    series.Priority,
+			UserID:      series.UserID,
+			ParentID:    task.ParentID,
+			SeriesID:    task.SeriesID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.DB.CopyTaskTags(ctx, database.CopyTaskTagsParams{NewTaskID: occurrence.ID, TaskID: task.ID})
+	if err != nil {
+		return err
+	}
+
+	return cfg.copyReminders(ctx, task, occurrence)
+}
+
+func (cfg *ApiConfig) HandleStopRecurrence(c echo.Context) error {
+	task, status, err := cfg.getOwnedTask(c.Request().Context(), c.Param("id"), c.Request().Header.Get("userID"))
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	if !task.SeriesID.Valid {
+		return respondWithError(c, http.StatusBadRequest, "task is not recurring")
+	}
+
+	updatedTask, err := cfg.DB.SetTaskSeries(
+		c.Request().Context(),
+		database.SetTaskSeriesParams{
+			SeriesID:  sql.NullString{},
+			UpdatedAt: time.Now().UTC(),
+			ID:        task.ID,
+			UserID:    task.UserID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt stop recurrence: %v", err))
+	}
+
+	return cfg.respondWithTask(c, http.StatusOK, updatedTask)
+}
+
+type RecurrencePreviewRes struct {
+	Rule        string   `json:"rule"`
+	Occurrences []string `json:"occurrences"`
+}
+
+func (cfg *ApiConfig) HandlePreviewRecurrence(c echo.Context) error {
+	rawRule := c.QueryParam("rule")
+	if rawRule == "" {
+		return respondWithError(c, http.StatusBadRequest, "rule needs to be specified as query parameter")
+	}
+
+	start := time.Now().UTC().Truncate(time.Second)
+	if rawStart := c.QueryParam("start"); rawStart != "" {
+		parsed, err := time.Parse(time.RFC3339, rawStart)
+		if err != nil {
+			return respondWithError(c, http.StatusBadRequest, "start must be an RFC3339 timestamp")
+		}
+		start = parsed
+	}
+
+	count := defaultPreviewCount
+	if rawCount := c.QueryParam("count"); rawCount != "" {
+		parsed, err := strconv.Atoi(rawCount)
+		if err != nil || parsed < 1 || parsed > maxPreviewCount {
+			return respondWithError(c, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxPreviewCount))
+		}
+		count = parsed
+	}
+
+	rule, err := parseRecurrenceRule(rawRule, start)
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, err.Error())
+	}
+
+	res := RecurrencePreviewRes{Rule: rule.OrigOptions.RRuleString(), Occurrences: []string{}}
+	next := rule.Iterator()
+	for len(res.Occurrences) < count {
+		occurrence, ok := next()
+		if !ok {
+			break
+		}
+		res.Occurrences = append(res.Occurrences, occurrence.Format(time.RFC3339))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
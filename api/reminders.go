@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/notify"
+)
+
+const maxReminderMinutesBefore = 60 * 24 * 30
+
+type CreateReminderReq struct {
+	MinutesBefore int64  `json:"minutes_before"`
+	Channel       string `json:"channel"`
+}
+
+type ReminderRes struct {
+	ID            string `json:"id"`
+	TaskID        string `json:"task_id"`
+	MinutesBefore int64  `json:"minutes_before"`
+	Channel       string `json:"channel"`
+	RemindAt      string `json:"remind_at"`
+	Status        string `json:"status"`
+	Attempts      int64  `json:"attempts"`
+	LastError     string `json:"last_error,omitempty"`
+	SentAt        string `json:"sent_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+func mapReminderToReminderRes(reminder database.TaskReminder) ReminderRes {
+	sentAt := ""
+	if reminder.SentAt.Valid {
+		sentAt = reminder.SentAt.Time.Format(time.RFC3339)
+	}
+
+	return ReminderRes{
+		ID:            reminder.ID,
+		TaskID:        reminder.TaskID,
+		MinutesBefore: reminder.MinutesBefore,
+		Channel:       reminder.Channel,
+		RemindAt:      reminder.RemindAt.Format(time.RFC3339),
+		Status:        reminder.Status,
+		Attempts:      reminder.Attempts,
+		LastError:     reminder.LastError,
+		SentAt:        sentAt,
+		CreatedAt:     reminder.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func remindAt(dueUntil time.Time, minutesBefore int64) time.Time {
+	return dueUntil.Add(-time.Duration(minutesBefore) * time.Minute).UTC()
+}
+
+// rescheduleReminders moves every reminder of task relative to its current due date, so
+// reminders that were already sent fire again for the new date.
+func (cfg *ApiConfig) rescheduleReminders(ctx context.Context, task database.Task) error {
+	reminders, err := cfg.DB.GetAllTaskReminders(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		err := cfg.DB.RescheduleTaskReminder(
+			ctx,
+			database.RescheduleTaskReminderParams{
+				RemindAt:  remindAt(task.DueUntil, reminder.MinutesBefore),
+				UpdatedAt: time.Now().UTC(),
+				ID:        reminder.ID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyReminders gives a newly generated occurrence of a recurring task the same reminders as
+// the occurrence it follows. Shares aren't copied, reminders of users the occurrence isn't
+// shared with are left out.
+func (cfg *ApiConfig) copyReminders(ctx context.Context, from, to database.Task) error {
+	reminders, err := cfg.DB.GetAllTaskReminders(ctx, from.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		_, err := cfg.DB.GetTaskByID(ctx, database.GetTaskByIDParams{ID: to.ID, UserID: reminder.UserID})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = cfg.DB.CreateTaskReminder(
+			ctx,
+			database.CreateTaskReminderParams{
+				ID:            uuid.NewString(),
+				CreatedAt:     time.Now().UTC(),
+				UpdatedAt:     time.Now().UTC(),
+				TaskID:        to.ID,
+				UserID:        reminder.UserID,
+				MinutesBefore: reminder.MinutesBefore,
+				Channel:       reminder.Channel,
+				RemindAt:      remindAt(to.DueUntil, reminder.MinutesBefore),
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cfg *ApiConfig) HandleCreateReminder(c echo.Context) error {
+	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
+
+	req := c.Request()
+	defer req.Body.Close()
+
+	reqBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt read req bytes")
+	}
+
+	var createReminderReq CreateReminderReq
+	if err := json.Unmarshal(reqBytes, &createReminderReq); err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	if createReminderReq.MinutesBefore < 0 || createReminderReq.MinutesBefore > maxReminderMinutesBefore {
+		return respondWithError(c, http.StatusBadRequest, fmt.Sprintf("minutes_before must be between 0 and %d", maxReminderMinutesBefore))
+	}
+
+	channel := createReminderReq.Channel
+	if channel == "" {
+		channel = notify.ChannelEmail
+	}
+	if channel != notify.ChannelEmail && channel != notify.ChannelWebhook {
+		return respondWithError(c, http.StatusBadRequest, "channel must be email or webhook")
+	}
+
+	task, err := cfg.DB.GetTaskByID(req.Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "task not found")
+	}
+
+	reminder, err := cfg.DB.CreateTaskReminder(
+		req.Context(),
+		database.CreateTaskReminderParams{
+			ID:            uuid.NewString(),
+			CreatedAt:     time.Now().UTC(),
+			UpdatedAt:     time.Now().UTC(),
+			TaskID:        task.ID,
+			UserID:        userID,
+			MinutesBefore: createReminderReq.MinutesBefore,
+			Channel:       channel,
+			RemindAt:      remindAt(task.DueUntil, createReminderReq.MinutesBefore),
+		},
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return respondWithError(c, http.StatusBadRequest, "reminder already exists")
+	}
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create reminder: %v", err))
+	}
+
+	return c.JSON(http.StatusCreated, mapReminderToReminderRes(reminder))
+}
+
+func (cfg *ApiConfig) HandleGetTaskReminders(c echo.Context) error {
+	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
+
+	task, err := cfg.DB.GetTaskByID(c.Request().Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "task not found")
+	}
+
+	reminders, err := cfg.DB.GetTaskReminders(c.Request().Context(), database.GetTaskRemindersParams{TaskID: task.ID, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve reminders: %v", err))
+	}
+
+	remindersRes := []ReminderRes{}
+	for _, reminder := range reminders {
+		remindersRes = append(remindersRes, mapReminderToReminderRes(reminder))
+	}
+
+	return c.JSON(http.StatusOK, remindersRes)
+}
+
+type DeleteReminderRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleDeleteReminder(c echo.Context) error {
+	id := c.Param("id")
+	reminderID := c.Param("reminderID")
+	userID := c.Request().Header.Get("userID")
+
+	task, err := cfg.DB.GetTaskByID(c.Request().Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "task not found")
+	}
+
+	err = cfg.DB.DeleteTaskReminder(
+		c.Request().Context(),
+		database.DeleteTaskReminderParams{ID: reminderID, TaskID: task.ID, UserID: userID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt delete reminder: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, DeleteReminderRes{Message: fmt.Sprintf("reminder %s deleted successfully", reminderID)})
+}
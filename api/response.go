@@ -1,6 +1,10 @@
 package api
 
 import (
+	"math"
+	"strconv"
+	"time"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -14,3 +18,8 @@ func respondWithError(c echo.Context, status int, msg string) error {
 	}
 	return c.JSON(status, err)
 }
+
+// setRetryAfter tells the client how many seconds to wait before trying again.
+func setRetryAfter(c echo.Context, retryAfter time.Duration) {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
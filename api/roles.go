@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+// Built in roles, see migrations 00019_roles.sql and 00020_login_attempts.sql for the
+// permissions each of them has.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+const (
+	PermissionUsersRead      = "users:read"
+	PermissionUsersDisable   = "users:disable"
+	PermissionUsersDelete    = "users:delete"
+	PermissionUsersUnlock    = "users:unlock"
+	PermissionSessionsRevoke = "sessions:revoke"
+	PermissionRolesAssign    = "roles:assign"
+)
+
+// RequirePermission only lets users through when one of their roles grants permission.
+// It goes after LoggedInMiddleware.
+func (cfg *ApiConfig) RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			granted, err := cfg.DB.CountUserPermission(
+				c.Request().Context(),
+				database.CountUserPermissionParams{UserID: c.Request().Header.Get("userID"), Permission: permission},
+			)
+			if err != nil || granted == 0 {
+				return respondWithError(c, http.StatusForbidden, "missing permission "+permission)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func (cfg *ApiConfig) userRoles(ctx context.Context, userID string) ([]string, error) {
+	userRoles, err := cfg.DB.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	for _, userRole := range userRoles {
+		roles = append(roles, userRole.Role)
+	}
+
+	return roles, nil
+}
+
+func (cfg *ApiConfig) rolesForUsers(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	roles := map[string][]string{}
+	for _, userID := range userIDs {
+		roles[userID] = []string{}
+	}
+
+	if len(userIDs) == 0 {
+		return roles, nil
+	}
+
+	userRoles, err := cfg.DB.GetRolesForUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userRole := range userRoles {
+		roles[userRole.UserID] = append(roles[userRole.UserID], userRole.Role)
+	}
+
+	return roles, nil
+}
+
+type RoleRes struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (cfg *ApiConfig) HandleGetRoles(c echo.Context) error {
+	roles, err := cfg.DB.GetRoles(c.Request().Context())
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve roles: %v", err))
+	}
+
+	rolePermissions, err := cfg.DB.GetRolePermissions(c.Request().Context())
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve permissions: %v", err))
+	}
+
+	permissions := map[string][]string{}
+	for _, rolePermission := range rolePermissions {
+		permissions[rolePermission.Role] = append(permissions[rolePermission.Role], rolePermission.Permission)
+	}
+
+	rolesRes := []RoleRes{}
+	for _, role := range roles {
+		granted := permissions[role.Name]
+		if granted == nil {
+			granted = []string{}
+		}
+
+		rolesRes = append(rolesRes, RoleRes{Name: role.Name, Description: role.Description, Permissions: granted})
+	}
+
+	return c.JSON(http.StatusOK, rolesRes)
+}
+
+type AssignRoleReq struct {
+	Role string `json:"role"`
+}
+
+func (cfg *ApiConfig) HandleAssignRole(c echo.Context) error {
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var assignRoleReq AssignRoleReq
+	if err := json.Unmarshal(requestBytes, &assignRoleReq); err != nil || assignRoleReq.Role == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	user, err := cfg.DB.GetUserByID(req.Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	role, err := cfg.DB.GetRoleByName(req.Context(), assignRoleReq.Role)
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, fmt.Sprintf("role %s doesnt exist", assignRoleReq.Role))
+	}
+
+	err = cfg.DB.AssignUserRole(
+		req.Context(),
+		database.AssignUserRoleParams{UserID: user.ID, Role: role.Name, CreatedAt: time.Now().UTC()},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt assign role: %v", err))
+	}
+
+	return cfg.respondWithAdminUser(c, http.StatusOK, user)
+}
+
+func (cfg *ApiConfig) HandleRemoveRole(c echo.Context) error {
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	role := c.Param("role")
+
+	roles, err := cfg.userRoles(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve roles: %v", err))
+	}
+	if !slices.Contains(roles, role) {
+		return respondWithError(c, http.StatusNotFound, fmt.Sprintf("user doesnt have role %s", role))
+	}
+
+	removed, err := cfg.DB.RemoveUserRole(c.Request().Context(), database.RemoveUserRoleParams{UserID: user.ID, Role: role})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt remove role: %v", err))
+	}
+	if removed == 0 {
+		return respondWithError(c, http.StatusConflict, "cant remove the admin role from the last admin")
+	}
+
+	return cfg.respondWithAdminUser(c, http.StatusOK, user)
+}
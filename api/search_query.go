@@ -0,0 +1,148 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// searchToken is a word, a "quoted phrase", one of AND, OR and NOT or a parenthesis of a search
+// query. Words and phrases ending with * match any word starting with them.
+type searchToken struct {
+	text     string
+	operator bool
+	prefix   bool
+}
+
+// matchExpression turns the q of a search into an FTS5 MATCH expression. Words and phrases are
+// quoted so the characters FTS5 gives a meaning to, like : ^ - or +, are searched for instead,
+// AND, OR, NOT, parentheses and a trailing * keep theirs. A query that isn't well formed is an
+// error before it gets to the database.
+func matchExpression(query string) (string, error) {
+	tokens, err := tokenizeSearchQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{}
+	depth := 0
+	expectOperand := true
+	for _, token := range tokens {
+		switch {
+		case token.operator && token.text == "(":
+			// FTS5 only ANDs words and phrases implicitly, a group needs it spelled out
+			if !expectOperand {
+				parts = append(parts, "AND")
+			}
+			depth++
+			expectOperand = true
+			parts = append(parts, token.text)
+		case token.operator && token.text == ")":
+			if expectOperand || depth == 0 {
+				return "", fmt.Errorf("unexpected )")
+			}
+			depth--
+			parts = append(parts, token.text)
+		case token.operator:
+			if expectOperand {
+				return "", fmt.Errorf("%s needs a word or phrase on both sides", token.text)
+			}
+			expectOperand = true
+			parts = append(parts, token.text)
+		default:
+			if !expectOperand && parts[len(parts)-1] == ")" {
+				parts = append(parts, "AND")
+			}
+			expectOperand = false
+			phrase := `"` + strings.ReplaceAll(token.text, `"`, `""`) + `"`
+			if token.prefix {
+				phrase += "*"
+			}
+			parts = append(parts, phrase)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "", fmt.Errorf("nothing to search for")
+	}
+	if expectOperand {
+		return "", fmt.Errorf("query cant end with %s", parts[len(parts)-1])
+	}
+	if depth > 0 {
+		return "", fmt.Errorf("missing )")
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+func tokenizeSearchQuery(query string) ([]searchToken, error) {
+	tokens := []searchToken{}
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(' || r == ')':
+			tokens = append(tokens, searchToken{text: string(r), operator: true})
+			i++
+			continue
+		}
+
+		var token searchToken
+		if r == '"' {
+			// a quote inside a phrase is written twice, like in FTS5 itself
+			var phrase strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					if i+1 < len(runes) && runes[i+1] == '"' {
+						phrase.WriteRune('"')
+						i++
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				phrase.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated phrase")
+			}
+			token.text = phrase.String()
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			token.text = string(runes[start:i])
+			if token.text == "AND" || token.text == "OR" || token.text == "NOT" {
+				token.operator = true
+				tokens = append(tokens, token)
+				continue
+			}
+		}
+
+		if i < len(runes) && runes[i] == '*' {
+			i++
+			token.prefix = true
+		} else if strings.HasSuffix(token.text, "*") {
+			token.text = strings.TrimSuffix(token.text, "*")
+			token.prefix = true
+		}
+		if !strings.ContainsFunc(token.text, isSearchable) {
+			return nil, fmt.Errorf("%q has no words to search for", token.text)
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// isSearchable reports whether the tokenizer of tasks_fts keeps r as part of a word.
+func isSearchable(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+const sessionTouchInterval = 5 * time.Minute
+
+// startSession logs the user in on the device making the request, the returned refresh
+// token starts a new token family whose id is the session id.
+func (cfg *ApiConfig) startSession(c echo.Context, userID, deviceName string) (LoginRes, error) {
+	session, err := cfg.DB.CreateSession(
+		c.Request().Context(),
+		database.CreateSessionParams{
+			ID:         uuid.NewString(),
+			UserID:     userID,
+			DeviceName: deviceName,
+			UserAgent:  c.Request().UserAgent(),
+			IpAddress:  c.RealIP(),
+			CreatedAt:  time.Now().UTC(),
+			LastUsedAt: time.Now().UTC(),
+			ExpiresAt:  time.Now().UTC().Add(refreshTokenLifetime),
+		},
+	)
+	if err != nil {
+		return LoginRes{}, fmt.Errorf("couldnt create session")
+	}
+
+	refreshToken, err := cfg.issueRefreshToken(c.Request().Context(), userID, session.ID)
+	if err != nil {
+		return LoginRes{}, fmt.Errorf("couldnt create refresh token")
+	}
+
+	jwtToken, err := auth.GenerateSessionJWTToken(userID, session.ID, cfg.Config.JWTSecret, time.Hour)
+	if err != nil {
+		return LoginRes{}, fmt.Errorf("couldnt generate jwt token")
+	}
+
+	return LoginRes{JWTToken: jwtToken, RefreshToken: refreshToken}, nil
+}
+
+// revokeSession ends a session and revokes every refresh token issued to it, returns false
+// when the session doesn't belong to the user or is already revoked.
+func (cfg *ApiConfig) revokeSession(c echo.Context, sessionID, userID string) (bool, error) {
+	revoked, err := cfg.DB.RevokeSession(
+		c.Request().Context(),
+		database.RevokeSessionParams{
+			RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			ID:        sessionID,
+			UserID:    userID,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	if revoked == 0 {
+		return false, nil
+	}
+
+	err = cfg.DB.RevokeRefreshTokenFamily(
+		c.Request().Context(),
+		database.RevokeRefreshTokenFamilyParams{UpdatedAt: time.Now().UTC(), FamilyID: sessionID},
+	)
+
+	return err == nil, err
+}
+
+// revokeUserSessions logs the user out on every device.
+func (cfg *ApiConfig) revokeUserSessions(ctx context.Context, userID string) error {
+	err := cfg.DB.RevokeUserSessions(
+		ctx,
+		database.RevokeUserSessionsParams{RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, UserID: userID},
+	)
+	if err != nil {
+		return err
+	}
+
+	return cfg.DB.RevokeRefreshToken(ctx, userID)
+}
+
+type SessionRes struct {
+	ID         string `json:"id"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
+
+func (cfg *ApiConfig) HandleGetSessions(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	currentSessionID := c.Request().Header.Get("sessionID")
+
+	sessions, err := cfg.DB.GetActiveUserSessions(
+		c.Request().Context(),
+		database.GetActiveUserSessionsParams{UserID: userID, ExpiresAt: time.Now().UTC()},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve sessions: %v", err))
+	}
+
+	sessionsRes := []SessionRes{}
+	for _, session := range sessions {
+		sessionsRes = append(sessionsRes, SessionRes{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastUsedAt: session.LastUsedAt.Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, sessionsRes)
+}
+
+type RevokeSessionRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleRevokeSession(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	sessionID := c.Param("id")
+
+	revoked, err := cfg.revokeSession(c, sessionID, userID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt revoke session: %v", err))
+	}
+	if !revoked {
+		return respondWithError(c, http.StatusNotFound, "session not found")
+	}
+
+	return c.JSON(http.StatusOK, RevokeSessionRes{Message: fmt.Sprintf("session %s revoked successfully", sessionID)})
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+type TaskTreeRes struct {
+	TaskRes
+	Subtasks []TaskTreeRes `json:"subtasks"`
+}
+
+func buildTaskTree(task TaskRes, childrenByParent map[string][]TaskRes) TaskTreeRes {
+	node := TaskTreeRes{TaskRes: task, Subtasks: []TaskTreeRes{}}
+	for _, child := range childrenByParent[task.ID] {
+		node.Subtasks = append(node.Subtasks, buildTaskTree(child, childrenByParent))
+	}
+
+	return node
+}
+
+func (cfg *ApiConfig) HandleCreateSubtask(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	parent, status, err := cfg.getOwnedTask(c.Request().Context(), c.Param("id"), userID)
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	params, rule, status, err := createTaskParamsFromReq(c.Request())
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+	params.ParentID = sql.NullString{String: parent.ID, Valid: true}
+
+	task, err := cfg.DB.CreateTask(c.Request().Context(), params)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create subtask: %v", err))
+	}
+
+	if rule != "" {
+		task, err = cfg.createSeriesForTask(c.Request().Context(), task, rule)
+		if err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create task series: %v", err))
+		}
+	}
+
+	return cfg.respondWithTask(c, http.StatusCreated, task)
+}
+
+func (cfg *ApiConfig) HandleGetTaskTree(c echo.Context) error {
+	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
+
+	root, err := cfg.DB.GetTaskByID(c.Request().Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "task not found")
+	}
+
+	tasks, err := cfg.DB.GetTaskTree(c.Request().Context(), database.GetTaskTreeParams{ID: root.ID, UserID: root.UserID})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task tree: %v", err))
+	}
+
+	tasksRes, err := cfg.mapTasksToTaskRes(c.Request().Context(), tasks)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task tags: %v", err))
+	}
+
+	var rootRes TaskRes
+	childrenByParent := map[string][]TaskRes{}
+	for _, task := range tasksRes {
+		if task.ID == root.ID {
+			rootRes = task
+			continue
+		}
+		childrenByParent[task.ParentID] = append(childrenByParent[task.ParentID], task)
+	}
+
+	return c.JSON(http.StatusOK, buildTaskTree(rootRes, childrenByParent))
+}
+
+type MoveTaskReq struct {
+	ParentID string `json:"parent_id"`
+}
+
+func (cfg *ApiConfig) HandleMoveTask(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	req := c.Request()
+	defer req.Body.Close()
+
+	reqBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt read req bytes")
+	}
+
+	var moveTaskReq MoveTaskReq
+	if err := json.Unmarshal(reqBytes, &moveTaskReq); err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	task, status, err := cfg.getOwnedTask(req.Context(), c.Param("id"), userID)
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	parentID := sql.NullString{}
+	if moveTaskReq.ParentID != "" {
+		parent, status, err := cfg.getOwnedTask(req.Context(), moveTaskReq.ParentID, userID)
+		if err != nil {
+			return respondWithError(c, status, err.Error())
+		}
+
+		ancestorIDs, err := cfg.DB.GetTaskAncestorIDs(req.Context(), parent.ID)
+		if err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task ancestors: %v", err))
+		}
+
+		for _, ancestorID := range ancestorIDs {
+			if ancestorID == task.ID {
+				return respondWithError(c, http.StatusBadRequest, "task cannot be moved under itself or one of its subtasks")
+			}
+		}
+
+		parentID = sql.NullString{String: parent.ID, Valid: true}
+	}
+
+	movedTask, err := cfg.DB.SetTaskParent(
+		req.Context(),
+		database.SetTaskParentParams{
+			ParentID:  parentID,
+			UpdatedAt: time.Now().UTC(),
+			ID:        task.ID,
+			UserID:    userID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt move task: %v", err))
+	}
+
+	return cfg.respondWithTask(c, http.StatusOK, movedTask)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type TagReq struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type TagRes struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func mapTagToTagRes(tag database.Tag) TagRes {
+	return TagRes{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		Color:     tag.Color,
+		CreatedAt: tag.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: tag.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func (cfg *ApiConfig) mapTasksToTaskRes(ctx context.Context, tasks []database.Task) ([]TaskRes, error) {
+	tasksRes := []TaskRes{}
+	if len(tasks) == 0 {
+		return tasksRes, nil
+	}
+
+	taskIDs := []string{}
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+	}
+
+	rows, err := cfg.DB.GetTagsForTasks(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsByTask := map[string][]TagRes{}
+	for _, row := range rows {
+		tagsByTask[row.TaskID] = append(tagsByTask[row.TaskID], mapTagToTagRes(row.Tag))
+	}
+
+	seriesIDs := []string{}
+	for _, task := range tasks {
+		if task.SeriesID.Valid {
+			seriesIDs = append(seriesIDs, task.SeriesID.String)
+		}
+	}
+
+	rulesBySeries := map[string]string{}
+	if len(seriesIDs) > 0 {
+		series, err := cfg.DB.GetTaskSeriesByIDs(ctx, seriesIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range series {
+			rulesBySeries[s.ID] = s.Rule
+		}
+	}
+
+	for _, task := range tasks {
+		taskRes := mapTaskToTaskRes(task)
+		if tags, ok := tagsByTask[task.ID]; ok {
+			taskRes.Tags = tags
+		}
+		taskRes.RecurrenceRule = rulesBySeries[task.SeriesID.String]
+		tasksRes = append(tasksRes, taskRes)
+	}
+
+	return tasksRes, nil
+}
+
+func (cfg *ApiConfig) respondWithTask(c echo.Context, status int, task database.Task) error {
+	tasksRes, err := cfg.mapTasksToTaskRes(c.Request().Context(), []database.Task{task})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task tags: %v", err))
+	}
+
+	return c.JSON(status, tasksRes[0])
+}
+
+func readTagReq(req *http.Request) (TagReq, error) {
+	defer req.Body.Close()
+
+	reqBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return TagReq{}, err
+	}
+
+	var tagReq TagReq
+	if err := json.Unmarshal(reqBytes, &tagReq); err != nil {
+		return TagReq{}, err
+	}
+
+	tagReq.Name = strings.TrimSpace(tagReq.Name)
+	if tagReq.Name == "" || (tagReq.Color != "" && !tagColorPattern.MatchString(tagReq.Color)) {
+		return TagReq{}, fmt.Errorf("request body invalid")
+	}
+
+	return tagReq, nil
+}
+
+func (cfg *ApiConfig) HandleCreateTag(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	tagReq, err := readTagReq(c.Request())
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	tag, err := cfg.DB.CreateTag(
+		c.Request().Context(),
+		database.CreateTagParams{
+			ID:        uuid.NewString(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      tagReq.Name,
+			Color:     tagReq.Color,
+			UserID:    userID,
+		},
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return respondWithError(c, http.StatusBadRequest, "tag with that name already exists")
+	}
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create tag: %v", err))
+	}
+
+	return c.JSON(http.StatusCreated, mapTagToTagRes(tag))
+}
+
+func (cfg *ApiConfig) HandleGetUsersTags(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	tags, err := cfg.DB.GetUsersTags(c.Request().Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve tags: %v", err))
+	}
+
+	tagsRes := []TagRes{}
+	for _, tag := range tags {
+		tagsRes = append(tagsRes, mapTagToTagRes(tag))
+	}
+
+	return c.JSON(http.StatusOK, tagsRes)
+}
+
+func (cfg *ApiConfig) HandleUpdateTag(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	tagReq, err := readTagReq(c.Request())
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	tag, err := cfg.DB.UpdateTagByID(
+		c.Request().Context(),
+		database.UpdateTagByIDParams{
+			Name:      tagReq.Name,
+			Color:     tagReq.Color,
+			UpdatedAt: time.Now().UTC(),
+			ID:        c.Param("id"),
+			UserID:    userID,
+		},
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return respondWithError(c, http.StatusBadRequest, "tag with that name already exists")
+	}
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "tag not found")
+	}
+
+	return c.JSON(http.StatusOK, mapTagToTagRes(tag))
+}
+
+type DeleteTagRes struct {
+	Message string `json:"message"`
+}
+
+// HandleDeleteTag deletes a tag, the foreign key on task_tags detaches it from every task it
+// was on.
+func (cfg *ApiConfig) HandleDeleteTag(c echo.Context) error {
+	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
+
+	_, err := cfg.DB.GetTagByID(c.Request().Context(), database.GetTagByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "tag not found")
+	}
+
+	err = cfg.DB.DeleteTagByID(c.Request().Context(), database.DeleteTagByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt delete tag: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, DeleteTagRes{Message: fmt.Sprintf("tag %s deleted successfully", id)})
+}
+
+type AttachTagReq struct {
+	TagID string `json:"tag_id"`
+}
+
+func (cfg *ApiConfig) HandleAttachTag(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	req := c.Request()
+	defer req.Body.Close()
+
+	reqBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt read req bytes")
+	}
+
+	var attachTagReq AttachTagReq
+	if err := json.Unmarshal(reqBytes, &attachTagReq); err != nil || attachTagReq.TagID == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	task, status, err := cfg.getOwnedTask(req.Context(), c.Param("id"), userID)
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	tag, err := cfg.DB.GetTagByID(req.Context(), database.GetTagByIDParams{ID: attachTagReq.TagID, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "tag not found")
+	}
+
+	err = cfg.DB.AttachTagToTask(req.Context(), database.AttachTagToTaskParams{TaskID: task.ID, TagID: tag.ID})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt attach tag: %v", err))
+	}
+
+	return cfg.respondWithTask(c, http.StatusOK, task)
+}
+
+func (cfg *ApiConfig) HandleDetachTag(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	task, status, err := cfg.getOwnedTask(c.Request().Context(), c.Param("id"), userID)
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	err = cfg.DB.DetachTagFromTask(c.Request().Context(), database.DetachTagFromTaskParams{TaskID: task.ID, TagID: c.Param("tagID")})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt detach tag: %v", err))
+	}
+
+	return cfg.respondWithTask(c, http.StatusOK, task)
+}
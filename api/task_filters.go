@@ -0,0 +1,110 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+func parseTimeFilter(c echo.Context, name string) (sql.NullTime, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return sql.NullTime{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+
+	return sql.NullTime{Time: parsed, Valid: true}, nil
+}
+
+func parsePriorityFilter(c echo.Context, name string) (sql.NullInt64, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	priority, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || priority < 0 {
+		return sql.NullInt64{}, fmt.Errorf("%s must be a non-negative number", name)
+	}
+
+	return sql.NullInt64{Int64: priority, Valid: true}, nil
+}
+
+func parseTaskFilters(c echo.Context, params *database.ListTasksParams) error {
+	query := c.QueryParams()
+
+	seenTags := map[string]bool{}
+	for _, tag := range query["tag"] {
+		if tag == "" {
+			return fmt.Errorf("tag cannot be empty")
+		}
+		if !seenTags[tag] {
+			seenTags[tag] = true
+			params.Tags = append(params.Tags, tag)
+		}
+	}
+
+	switch c.QueryParam("tag_match") {
+	case "", "any":
+		params.MatchAllTags = false
+	case "all":
+		params.MatchAllTags = true
+	default:
+		return fmt.Errorf("tag_match must be any or all")
+	}
+
+	for _, status := range query["status"] {
+		if !isValidTaskStatus(status) {
+			return fmt.Errorf("invalid task status: %s", status)
+		}
+		params.Statuses = append(params.Statuses, status)
+	}
+
+	var err error
+	if params.PriorityMin, err = parsePriorityFilter(c, "priority_min"); err != nil {
+		return err
+	}
+	if params.PriorityMax, err = parsePriorityFilter(c, "priority_max"); err != nil {
+		return err
+	}
+	if params.PriorityMin.Valid && params.PriorityMax.Valid && params.PriorityMin.Int64 > params.PriorityMax.Int64 {
+		return fmt.Errorf("priority_min cannot be greater than priority_max")
+	}
+
+	timeFilters := []struct {
+		name   string
+		target *sql.NullTime
+	}{
+		{"due_before", &params.DueBefore},
+		{"due_after", &params.DueAfter},
+		{"created_before", &params.CreatedBefore},
+		{"created_after", &params.CreatedAfter},
+		{"updated_before", &params.UpdatedBefore},
+		{"updated_after", &params.UpdatedAfter},
+	}
+	for _, filter := range timeFilters {
+		if *filter.target, err = parseTimeFilter(c, filter.name); err != nil {
+			return err
+		}
+	}
+
+	if raw := c.QueryParam("overdue"); raw != "" {
+		overdue, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("overdue must be true or false")
+		}
+		if overdue {
+			params.OverdueAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+type ShareTaskReq struct {
+	Email string `json:"email"`
+}
+
+type TaskShareRes struct {
+	TaskID    string `json:"task_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+func mapTaskShareToTaskShareRes(share database.GetTaskSharesRow) TaskShareRes {
+	return TaskShareRes{
+		TaskID:    share.TaskID,
+		UserID:    share.UserID,
+		Username:  share.Username,
+		Email:     share.Email,
+		CreatedAt: share.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func (cfg *ApiConfig) HandleShareTask(c echo.Context) error {
+	req := c.Request()
+	defer req.Body.Close()
+
+	reqBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt read req bytes")
+	}
+
+	var shareTaskReq ShareTaskReq
+	if err := json.Unmarshal(reqBytes, &shareTaskReq); err != nil || shareTaskReq.Email == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	task, status, err := cfg.getOwnedTask(c.Request().Context(), c.Param("id"), c.Request().Header.Get("userID"))
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	user, err := cfg.DB.GetUserByEmail(req.Context(), shareTaskReq.Email)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	if user.ID == task.UserID {
+		return respondWithError(c, http.StatusBadRequest, "task cannot be shared with its owner")
+	}
+
+	err = cfg.DB.CreateTaskShare(
+		req.Context(),
+		database.CreateTaskShareParams{
+			SharedWithID: user.ID,
+			CreatedAt:    time.Now(),
+			TaskID:       task.ID,
+			OwnerID:      task.UserID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt share task: %v", err))
+	}
+
+	return c.JSON(
+		http.StatusCreated,
+		TaskShareRes{
+			TaskID:    task.ID,
+			UserID:    user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			CreatedAt: time.Now().Format(time.RFC3339),
+		},
+	)
+}
+
+func (cfg *ApiConfig) HandleGetTaskShares(c echo.Context) error {
+	task, status, err := cfg.getOwnedTask(c.Request().Context(), c.Param("id"), c.Request().Header.Get("userID"))
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	shares, err := cfg.DB.GetTaskShares(
+		c.Request().Context(),
+		database.GetTaskSharesParams{TaskID: task.ID, UserID: task.UserID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task shares: %v", err))
+	}
+
+	sharesRes := []TaskShareRes{}
+	for _, share := range shares {
+		sharesRes = append(sharesRes, mapTaskShareToTaskShareRes(share))
+	}
+
+	return c.JSON(http.StatusOK, sharesRes)
+}
+
+type UnshareTaskRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleUnshareTask(c echo.Context) error {
+	task, status, err := cfg.getOwnedTask(c.Request().Context(), c.Param("id"), c.Request().Header.Get("userID"))
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	sharedWithID := c.Param("userID")
+
+	err = cfg.DB.DeleteTaskShare(
+		c.Request().Context(),
+		database.DeleteTaskShareParams{
+			TaskID:       task.ID,
+			SharedWithID: sharedWithID,
+			OwnerID:      task.UserID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt unshare task: %v", err))
+	}
+
+	// the reminders would keep sending the task to someone who can't see it anymore
+	err = cfg.DB.DeleteUsersTaskReminders(
+		c.Request().Context(),
+		database.DeleteUsersTaskRemindersParams{TaskID: task.ID, UserID: sharedWithID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt delete reminders: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, UnshareTaskRes{Message: fmt.Sprintf("task %s is no longer shared with user %s", task.ID, sharedWithID)})
+}
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,27 +16,54 @@ import (
 	"github.com/magicznykacpur/taskin-backend/internal/database"
 )
 
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusBlocked    = "blocked"
+	TaskStatusDone       = "done"
+	TaskStatusCancelled  = "cancelled"
+)
+
+func isValidTaskStatus(status string) bool {
+	switch status {
+	case TaskStatusTodo, TaskStatusInProgress, TaskStatusBlocked, TaskStatusDone, TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
 type CreateTaskReq struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Priority    int64  `json:"priority"`
-	Category    string `json:"category"`
 	DueUntil    string `json:"due_until"`
+	// RecurrenceRule is an iCalendar RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO", anchored at DueUntil.
+	RecurrenceRule string `json:"recurrence_rule,omitempty"`
 }
 
 type TaskRes struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Priority    int64  `json:"priority"`
-	Category    string `json:"category"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
-	DueUntil    string `json:"due_until"`
-	UserID      string `json:"user_id"`
+	ID             string   `json:"id"`
+	Title          string   `json:"title"`
+	Description    string   `json:"description"`
+	Priority       int64    `json:"priority"`
+	Tags           []TagRes `json:"tags"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+	DueUntil       string   `json:"due_until"`
+	Status         string   `json:"status"`
+	CompletedAt    string   `json:"completed_at,omitempty"`
+	ParentID       string   `json:"parent_id,omitempty"`
+	SeriesID       string   `json:"series_id,omitempty"`
+	RecurrenceRule string   `json:"recurrence_rule,omitempty"`
+	UserID         string   `json:"user_id"`
 }
 
 func mapTaskToTaskRes(task database.Task) TaskRes {
+	completedAt := ""
+	if task.CompletedAt.Valid {
+		completedAt = task.CompletedAt.Time.Format(time.RFC3339)
+	}
+
 	return TaskRes{
 		ID:          task.ID,
 		CreatedAt:   task.CreatedAt.Format(time.RFC3339),
@@ -41,152 +72,294 @@ func mapTaskToTaskRes(task database.Task) TaskRes {
 		Title:       task.Title,
 		Description: task.Description,
 		Priority:    task.Priority,
-		Category:    task.Category,
+		Tags:        []TagRes{},
+		Status:      task.Status,
+		CompletedAt: completedAt,
+		ParentID:    task.ParentID.String,
+		SeriesID:    task.SeriesID.String,
 		UserID:      task.UserID,
 	}
 }
 
-func (cfg *ApiConfig) HandleCreateTask(c echo.Context) error {
-	req := c.Request()
+// createTaskParamsFromReq also returns the normalized recurrence rule of the request, if any.
+func createTaskParamsFromReq(req *http.Request) (database.CreateTaskParams, string, int, error) {
 	defer req.Body.Close()
 
 	reqBytes, err := io.ReadAll(req.Body)
 	if err != nil {
-		return respondWithError(c, http.StatusInternalServerError, "couldnt read req bytes")
+		return database.CreateTaskParams{}, "", http.StatusInternalServerError, fmt.Errorf("couldnt read req bytes")
 	}
 
 	var createTaskReq CreateTaskReq
 	if err := json.Unmarshal(reqBytes, &createTaskReq); err != nil {
-		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+		return database.CreateTaskParams{}, "", http.StatusBadRequest, fmt.Errorf("request body invalid")
 	}
 
 	if createTaskReq.Title == "" ||
 		createTaskReq.Description == "" ||
 		createTaskReq.Priority < 0 ||
-		createTaskReq.Category == "" ||
 		createTaskReq.DueUntil == "" {
 
-		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+		return database.CreateTaskParams{}, "", http.StatusBadRequest, fmt.Errorf("request body invalid")
 	}
 
 	dueUntil, err := time.Parse(time.RFC3339, createTaskReq.DueUntil)
 	if err != nil {
-		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt parse date: %v", err))
-	}
-
-	task, err := cfg.DB.CreateTask(
-		req.Context(),
-		database.CreateTaskParams{
-			ID:          uuid.NewString(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			DueUntil:    dueUntil,
-			Title:       createTaskReq.Title,
-			Description: createTaskReq.Description,
-			Priority:    createTaskReq.Priority,
-			Category:    createTaskReq.Category,
-			UserID:      req.Header.Get("userID"),
-		},
-	)
+		return database.CreateTaskParams{}, "", http.StatusInternalServerError, fmt.Errorf("couldnt parse date: %v", err)
+	}
+
+	rule := ""
+	if createTaskReq.RecurrenceRule != "" {
+		rule, err = normalizeRecurrenceRule(createTaskReq.RecurrenceRule, dueUntil)
+		if err != nil {
+			return database.CreateTaskParams{}, "", http.StatusBadRequest, err
+		}
+	}
+
+	return database.CreateTaskParams{
+		ID:          uuid.NewString(),
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		DueUntil:    dueUntil.UTC(),
+		Title:       createTaskReq.Title,
+		Description: createTaskReq.Description,
+		Priority:    createTaskReq.Priority,
+		UserID:      req.Header.Get("userID"),
+	}, rule, http.StatusCreated, nil
+}
+
+func (cfg *ApiConfig) HandleCreateTask(c echo.Context) error {
+	params, rule, status, err := createTaskParamsFromReq(c.Request())
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	task, err := cfg.DB.CreateTask(c.Request().Context(), params)
 	if err != nil {
 		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create task: %v", err))
 	}
 
-	return c.JSON(http.StatusCreated, mapTaskToTaskRes(task))
+	if rule != "" {
+		task, err = cfg.createSeriesForTask(c.Request().Context(), task, rule)
+		if err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create task series: %v", err))
+		}
+	}
+
+	return cfg.respondWithTask(c, http.StatusCreated, task)
+}
+
+func (cfg *ApiConfig) getOwnedTask(ctx context.Context, id, userID string) (database.Task, int, error) {
+	task, err := cfg.DB.GetTaskByID(ctx, database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return database.Task{}, http.StatusNotFound, fmt.Errorf("task not found")
+	}
+
+	if task.UserID != userID {
+		return database.Task{}, http.StatusForbidden, fmt.Errorf("only the task owner can do that")
+	}
+
+	return task, http.StatusOK, nil
 }
 
 func (cfg *ApiConfig) HandleGetTaskByID(c echo.Context) error {
 	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
 
-	task, err := cfg.DB.GetTaskByID(c.Request().Context(), id)
+	task, err := cfg.DB.GetTaskByID(c.Request().Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
 	if err != nil {
 		return respondWithError(c, http.StatusNotFound, "task not found")
 	}
 
-	return c.JSON(http.StatusOK, mapTaskToTaskRes(task))
+	return cfg.respondWithTask(c, http.StatusOK, task)
+}
+
+func cursorValueForTask(task database.Task, sortBy string) string {
+	switch sortBy {
+	case "due_until":
+		return task.DueUntil.Format(time.RFC3339Nano)
+	case "priority":
+		return strconv.FormatInt(task.Priority, 10)
+	case "updated_at":
+		return task.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return task.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+func parseCursorValue(value, sortBy string) (interface{}, error) {
+	if sortBy == "priority" {
+		return strconv.ParseInt(value, 10, 64)
+	}
+	return time.Parse(time.RFC3339Nano, value)
 }
 
 func (cfg *ApiConfig) HandleGetAllUsersTasks(c echo.Context) error {
 	userID := c.Request().Header.Get("userID")
 
-	tasks, err := cfg.DB.GetAllUsersTasks(c.Request().Context(), userID)
+	limit, err := parsePageLimit(c)
 	if err != nil {
-		return respondWithError(c, http.StatusNotFound, "no tasks were found for user with this id")
+		return respondWithError(c, http.StatusBadRequest, err.Error())
 	}
 
-	tasksRes := []TaskRes{}
-	for _, task := range tasks {
-		tasksRes = append(tasksRes, mapTaskToTaskRes(task))
+	sortBy := c.QueryParam("sort")
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	if _, ok := database.TaskSortColumns[sortBy]; !ok {
+		return respondWithError(c, http.StatusBadRequest, "sort must be one of due_until, priority, created_at, updated_at")
 	}
 
-	return c.JSON(http.StatusOK, tasksRes)
-}
+	order := c.QueryParam("order")
+	if order == "" {
+		order = "desc"
+	}
+	if order != "asc" && order != "desc" {
+		return respondWithError(c, http.StatusBadRequest, "order must be asc or desc")
+	}
 
-func (cfg *ApiConfig) HandleGetTasksWhereTitleOrDescriptionLike(c echo.Context) error {
-	title := c.QueryParam("title")
-	description := c.QueryParam("description")
+	params := database.ListTasksParams{
+		UserID: userID,
+		SortBy: sortBy,
+		Desc:   order == "desc",
+		Limit:  limit + 1,
+	}
 
-	if title != "" && description != "" {
-		tasks, err := cfg.DB.GetTaskByTitleAndDescription(
-			c.Request().Context(),
-			database.GetTaskByTitleAndDescriptionParams{
-				Title:       "%" + title + "%",
-				Description: "%" + description + "%",
-			},
-		)
+	if err := parseTaskFilters(c, &params); err != nil {
+		return respondWithError(c, http.StatusBadRequest, err.Error())
+	}
+
+	if rawCursor := c.QueryParam("cursor"); rawCursor != "" {
+		cursor, err := decodeCursor(rawCursor)
 		if err != nil {
-			return respondWithError(c, http.StatusNotFound, "no tasks found with this title and description")
+			return respondWithError(c, http.StatusBadRequest, err.Error())
+		}
+		if cursor.SortBy != params.SortBy || cursor.Desc != params.Desc || cursor.ID == "" {
+			return respondWithError(c, http.StatusBadRequest, "cursor does not match requested sort")
 		}
 
-		tasksRes := []TaskRes{}
-		for _, task := range tasks {
-			tasksRes = append(tasksRes, mapTaskToTaskRes(task))
+		afterValue, err := parseCursorValue(cursor.Value, cursor.SortBy)
+		if err != nil {
+			return respondWithError(c, http.StatusBadRequest, "cursor malformed")
 		}
 
-		return c.JSON(http.StatusOK, tasksRes)
+		params.AfterValue = afterValue
+		params.AfterID = cursor.ID
 	}
 
-	if title != "" {
-		tasks, err := cfg.DB.GetTasksByTitle(c.Request().Context(), fmt.Sprintf("%%%s%%", title))
-		if err != nil {
-			return respondWithError(c, http.StatusNotFound, "no tasks found with this title")
-		}
+	tasks, err := cfg.DB.ListTasks(c.Request().Context(), params)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve tasks: %v", err))
+	}
 
-		tasksRes := []TaskRes{}
-		for _, task := range tasks {
-			tasksRes = append(tasksRes, mapTaskToTaskRes(task))
-		}
+	page := PageRes[TaskRes]{Items: []TaskRes{}}
+	if int64(len(tasks)) > limit {
+		tasks = tasks[:limit]
+		last := tasks[len(tasks)-1]
+		page.NextCursor = encodeCursor(pageCursor{
+			SortBy: params.SortBy,
+			Desc:   params.Desc,
+			Value:  cursorValueForTask(last, params.SortBy),
+			ID:     last.ID,
+		})
+	}
 
-		return c.JSON(http.StatusOK, tasksRes)
+	page.Items, err = cfg.mapTasksToTaskRes(c.Request().Context(), tasks)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task tags: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+type SearchResultRes struct {
+	Task           TaskRes `json:"task"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+	Score          float64 `json:"score"`
+}
+
+func (cfg *ApiConfig) HandleSearchTasks(c echo.Context) error {
+	query := strings.TrimSpace(c.QueryParam("q"))
+	userID := c.Request().Header.Get("userID")
+
+	if query == "" {
+		return respondWithError(c, http.StatusBadRequest, "q needs to be specified as query parameter")
+	}
+
+	match, err := matchExpression(query)
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, fmt.Sprintf("invalid search query: %v", err))
+	}
+
+	limit, err := parsePageLimit(c)
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, err.Error())
 	}
 
-	if description != "" {
-		tasks, err := cfg.DB.GetTasksByDescription(c.Request().Context(), fmt.Sprintf("%%%s%%", description))
+	offset := int64(0)
+	if rawCursor := c.QueryParam("cursor"); rawCursor != "" {
+		cursor, err := decodeCursor(rawCursor)
 		if err != nil {
-			return respondWithError(c, http.StatusNotFound, "no tasks found with this description")
+			return respondWithError(c, http.StatusBadRequest, err.Error())
 		}
+		offset = cursor.Offset
+	}
 
-		tasksRes := []TaskRes{}
-		for _, task := range tasks {
-			tasksRes = append(tasksRes, mapTaskToTaskRes(task))
-		}
+	results, err := cfg.DB.SearchTasks(
+		c.Request().Context(),
+		database.SearchTasksParams{Query: match, UserID: userID, Limit: limit + 1, Offset: offset},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt search tasks: %v", err))
+	}
+
+	page := PageRes[SearchResultRes]{Items: []SearchResultRes{}}
+	if int64(len(results)) > limit {
+		results = results[:limit]
+		page.NextCursor = encodeCursor(pageCursor{Offset: offset + limit})
+	}
 
-		return c.JSON(http.StatusOK, tasksRes)
+	tasks := []database.Task{}
+	for _, result := range results {
+		tasks = append(tasks, result.Task)
 	}
 
-	return respondWithError(c, http.StatusBadRequest, "title or description need to be specified as query parameters")
+	tasksRes, err := cfg.mapTasksToTaskRes(c.Request().Context(), tasks)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt retrieve task tags: %v", err))
+	}
+
+	for i, result := range results {
+		page.Items = append(page.Items, SearchResultRes{
+			Task:           tasksRes[i],
+			TitleHighlight: result.TitleHighlight,
+			Snippet:        result.DescriptionSnippet,
+			Score:          result.Score,
+		})
+	}
+
+	return c.JSON(http.StatusOK, page)
 }
 
 type UpdateTaskReq struct {
 	Title       string `json:"title,omitempty"`
 	Description string `json:"description,omitempty"`
 	Priority    int64  `json:"priority,omitempty"`
-	Category    string `json:"category,omitempty"`
 	DueUntil    string `json:"due_until,omitempty"`
+	Status      string `json:"status,omitempty"`
+	// RecurrenceRule makes a one-off task recurring, or changes the rule of a recurring
+	// task when Scope is "future".
+	RecurrenceRule string `json:"recurrence_rule,omitempty"`
+	// Scope is "this" (default) to edit only this occurrence of a recurring task, or
+	// "future" to also apply the edit to every occurrence generated after it. Only the owner
+	// can edit future occurrences or set RecurrenceRule.
+	Scope string `json:"scope,omitempty"`
 }
 
 func (cfg *ApiConfig) HandleUpdateTask(c echo.Context) error {
 	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
 
 	req := c.Request()
 	defer req.Body.Close()
@@ -201,36 +374,82 @@ func (cfg *ApiConfig) HandleUpdateTask(c echo.Context) error {
 		return respondWithError(c, http.StatusBadRequest, "request body invalid")
 	}
 
-	task, err := cfg.DB.GetTaskByID(req.Context(), id)
+	if updateTaskReq.Status != "" && !isValidTaskStatus(updateTaskReq.Status) {
+		return respondWithError(c, http.StatusBadRequest, fmt.Sprintf("invalid task status: %s", updateTaskReq.Status))
+	}
+
+	scope := updateTaskReq.Scope
+	if scope == "" {
+		scope = RecurrenceScopeThis
+	}
+	if scope != RecurrenceScopeThis && scope != RecurrenceScopeFuture {
+		return respondWithError(c, http.StatusBadRequest, "scope must be this or future")
+	}
+
+	task, err := cfg.DB.GetTaskByID(req.Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
 	if err != nil {
 		return respondWithError(c, http.StatusNotFound, "task not found")
 	}
 
-	title, description, priority, category, dueUntil, err := retrieveValuesFromTaskUpdateReq(updateTaskReq, task)
+	// the series belongs to the owner, whoever the task is shared with edits single occurrences
+	if (scope == RecurrenceScopeFuture || updateTaskReq.RecurrenceRule != "") && task.UserID != userID {
+		return respondWithError(c, http.StatusForbidden, "only the task owner can change its recurrence")
+	}
+	if scope == RecurrenceScopeFuture && !task.SeriesID.Valid {
+		return respondWithError(c, http.StatusBadRequest, "task is not recurring")
+	}
+	if updateTaskReq.RecurrenceRule != "" && task.SeriesID.Valid && scope != RecurrenceScopeFuture {
+		return respondWithError(c, http.StatusBadRequest, "recurrence_rule can only be changed for future occurrences")
+	}
+
+	params, err := retrieveValuesFromTaskUpdateReq(updateTaskReq, task, userID)
 	if err != nil {
 		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt parse time: %v", err))
 	}
 
-	updatedTask, err := cfg.DB.UpdateTaskByID(
-		req.Context(),
-		database.UpdateTaskByIDParams{
-			Title:       title,
-			Description: description,
-			Priority:    priority,
-			Category:    category,
-			UpdatedAt:   time.Now(),
-			DueUntil:    dueUntil,
-			ID:          id,
-		},
-	)
+	// the rule is anchored at the due date the task has after the update
+	rule := ""
+	if updateTaskReq.RecurrenceRule != "" {
+		rule, err = normalizeRecurrenceRule(updateTaskReq.RecurrenceRule, params.DueUntil)
+		if err != nil {
+			return respondWithError(c, http.StatusBadRequest, err.Error())
+		}
+	}
+
+	updatedTask, err := cfg.DB.UpdateTaskByID(req.Context(), params)
 	if err != nil {
 		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("coudlnt update task: %v", err))
 	}
 
-	return c.JSON(http.StatusOK, mapTaskToTaskRes(updatedTask))
+	if rule != "" && !task.SeriesID.Valid {
+		updatedTask, err = cfg.createSeriesForTask(req.Context(), updatedTask, rule)
+		if err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create task series: %v", err))
+		}
+	}
+
+	if !updatedTask.DueUntil.Equal(task.DueUntil) {
+		if err := cfg.rescheduleReminders(req.Context(), updatedTask); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt reschedule reminders: %v", err))
+		}
+	}
+
+	if scope == RecurrenceScopeFuture {
+		if err := cfg.updateFutureOccurrences(req.Context(), task, updatedTask, rule); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt update future occurrences: %v", err))
+		}
+	}
+
+	if task.Status != TaskStatusDone && updatedTask.Status == TaskStatusDone {
+		if err := cfg.scheduleNextOccurrence(req.Context(), updatedTask); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt schedule next occurrence: %v", err))
+		}
+	}
+
+	return cfg.respondWithTask(c, http.StatusOK, updatedTask)
 }
 
-func retrieveValuesFromTaskUpdateReq(updateTaskReq UpdateTaskReq, task database.Task) (string, string, int64, string, time.Time, error) {
+func retrieveValuesFromTaskUpdateReq(updateTaskReq UpdateTaskReq, task database.Task, userID string) (database.UpdateTaskByIDParams, error) {
 	title := updateTaskReq.Title
 	if title == "" {
 		title = task.Title
@@ -246,36 +465,157 @@ func retrieveValuesFromTaskUpdateReq(updateTaskReq UpdateTaskReq, task database.
 		priority = task.Priority
 	}
 
-	category := updateTaskReq.Category
-	if category == "" {
-		category = task.Category
+	dueUntil := task.DueUntil
+	if updateTaskReq.DueUntil != "" {
+		parsed, err := time.Parse(time.RFC3339, updateTaskReq.DueUntil)
+		if err != nil {
+			return database.UpdateTaskByIDParams{}, err
+		}
+		dueUntil = parsed.UTC()
+	}
+
+	status := updateTaskReq.Status
+	if status == "" {
+		status = task.Status
+	}
+
+	completedAt := sql.NullTime{}
+	if status == TaskStatusDone {
+		completedAt = task.CompletedAt
+		if !completedAt.Valid {
+			completedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		}
+	}
+
+	return database.UpdateTaskByIDParams{
+		Title:       title,
+		Description: description,
+		Priority:    priority,
+		UpdatedAt:   time.Now().UTC(),
+		DueUntil:    dueUntil,
+		Status:      status,
+		CompletedAt: completedAt,
+		ID:          task.ID,
+		UserID:      userID,
+	}, nil
+}
+
+func (cfg *ApiConfig) HandleCompleteTask(c echo.Context) error {
+	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
+
+	task, err := cfg.DB.GetTaskByID(c.Request().Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "task not found")
+	}
+
+	if task.Status == TaskStatusDone {
+		return cfg.respondWithTask(c, http.StatusOK, task)
+	}
+
+	completedTask, err := cfg.DB.CompleteTaskByID(
+		c.Request().Context(),
+		database.CompleteTaskByIDParams{
+			CompletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt:   time.Now().UTC(),
+			ID:          task.ID,
+			UserID:      userID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt complete task: %v", err))
+	}
+
+	if err := cfg.scheduleNextOccurrence(c.Request().Context(), completedTask); err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt schedule next occurrence: %v", err))
+	}
+
+	return cfg.respondWithTask(c, http.StatusOK, completedTask)
+}
+
+func (cfg *ApiConfig) HandleReopenTask(c echo.Context) error {
+	id := c.Param("id")
+	userID := c.Request().Header.Get("userID")
+
+	task, err := cfg.DB.GetTaskByID(c.Request().Context(), database.GetTaskByIDParams{ID: id, UserID: userID})
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "task not found")
+	}
+
+	if task.Status != TaskStatusDone && task.Status != TaskStatusCancelled {
+		return respondWithError(c, http.StatusBadRequest, "only done or cancelled tasks can be reopened")
 	}
 
-	dueUntil, err := time.Parse(time.RFC3339, updateTaskReq.DueUntil)
+	reopenedTask, err := cfg.DB.ReopenTaskByID(
+		c.Request().Context(),
+		database.ReopenTaskByIDParams{
+			UpdatedAt: time.Now().UTC(),
+			ID:        task.ID,
+			UserID:    userID,
+		},
+	)
 	if err != nil {
-		return "", "", -1, "", time.Time{}, err
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt reopen task: %v", err))
 	}
 
-	return title, description, priority, category, dueUntil, nil
+	return cfg.respondWithTask(c, http.StatusOK, reopenedTask)
 }
 
 type DeleteTaskRes struct {
 	Message string `json:"message"`
 }
 
+// HandleDeleteTask deletes a task with its shares, tags and reminders. Its subtasks are deleted
+// along with it, the foreign key on parent_id cascades, unless children=reparent moves them up
+// to the task's parent first.
 func (cfg *ApiConfig) HandleDeleteTask(c echo.Context) error {
 	id := c.Param("id")
 	userID := c.Request().Header.Get("userID")
 
-	_, err := cfg.DB.GetTaskByID(c.Request().Context(), id)
+	children := c.QueryParam("children")
+	if children == "" {
+		children = "cascade"
+	}
+	if children != "cascade" && children != "reparent" {
+		return respondWithError(c, http.StatusBadRequest, "children must be cascade or reparent")
+	}
+
+	task, status, err := cfg.getOwnedTask(c.Request().Context(), id, userID)
+	if err != nil {
+		return respondWithError(c, status, err.Error())
+	}
+
+	// the subtasks mustn't end up moved while the task they were moved off of is still there
+	tx, err := cfg.Conn.BeginTx(c.Request().Context(), nil)
 	if err != nil {
-		return respondWithError(c, http.StatusNotFound, fmt.Sprintf("coudlnt delete task: %v", err))
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt start transaction: %v", err))
 	}
+	defer tx.Rollback()
+	queries := cfg.DB.WithTx(tx)
 
-	err = cfg.DB.DeleteTaskByID(c.Request().Context(), database.DeleteTaskByIDParams{ID: id, UserID: userID})
+	if children == "reparent" {
+		err = queries.ReparentSubtasks(
+			c.Request().Context(),
+			database.ReparentSubtasksParams{
+				NewParentID: task.ParentID,
+				UpdatedAt:   time.Now().UTC(),
+				ParentID:    sql.NullString{String: task.ID, Valid: true},
+				UserID:      userID,
+			},
+		)
+		if err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt reparent subtasks: %v", err))
+		}
+	}
+
+	err = queries.DeleteTaskByID(c.Request().Context(), database.DeleteTaskByIDParams{ID: id, UserID: userID})
 	if err != nil {
 		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("coudlnt delete task: %v", err))
 	}
 
+	if err := tx.Commit(); err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt delete task: %v", err))
+	}
+
 	return c.JSON(http.StatusOK, DeleteTaskRes{Message: fmt.Sprintf("task %s deleted successfully", id)})
 }
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAdmin logs in a user, makes them an admin and adds a regular user "member".
+func setupAdmin(t *testing.T) (*api.ApiConfig, api.LoginRes, string) {
+	cfg, login := setupLoggedInUser(t)
+	adminID := loggedInUserID(t, cfg)
+
+	assignTestRole(t, cfg, adminID, api.RoleAdmin)
+	createTestUser(cfg, "member", "member", "member@test.com")
+
+	return cfg, login, adminID
+}
+
+func callAdmin(t *testing.T, handler echo.HandlerFunc, method, path, userID string) *httptest.ResponseRecorder {
+	c, rec := setupEcho(method, path, "")
+	c.SetParamNames("id")
+	c.SetParamValues(userID)
+
+	err := handler(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func decodeAdminUserRes(rec *httptest.ResponseRecorder) api.AdminUserDetailRes {
+	var userRes api.AdminUserDetailRes
+	json.Unmarshal(rec.Body.Bytes(), &userRes)
+
+	return userRes
+}
+
+func TestAdminListUsers(t *testing.T) {
+	cfg, _, _ := setupAdmin(t)
+	createTestUser(cfg, "other", "other member", "other@test.com")
+	createTestTask(t, cfg, "member")
+	createTestTask(t, cfg, "member")
+
+	c, rec := setupEcho(http.MethodGet, "/api/admin/users?q=MEMBER&limit=1", "")
+	assert.NoError(t, cfg.HandleAdminGetUsers(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var page api.PageRes[api.AdminUserRes]
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "member", page.Items[0].Username)
+	assert.Equal(t, int64(2), page.Items[0].TaskCount)
+	assert.NotEmpty(t, page.NextCursor)
+
+	c, rec = setupEcho(http.MethodGet, "/api/admin/users?q=member&limit=1&cursor="+page.NextCursor, "")
+	assert.NoError(t, cfg.HandleAdminGetUsers(c))
+
+	page = api.PageRes[api.AdminUserRes]{}
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "other member", page.Items[0].Username)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestAdminGetUserTaskCounts(t *testing.T) {
+	cfg, _, _ := setupAdmin(t)
+	task := createTestTask(t, cfg, "member")
+	createTestTask(t, cfg, "member")
+
+	c, _ := setupTaskEcho(http.MethodPost, "/api/tasks/:id/complete", "", "member", task.ID)
+	assert.NoError(t, cfg.HandleCompleteTask(c))
+
+	rec := callAdmin(t, cfg.HandleAdminGetUser, http.MethodGet, "/api/admin/users/member", "member")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	userRes := decodeAdminUserRes(rec)
+	assert.Equal(t, int64(2), userRes.TaskCount)
+	assert.Equal(t, map[string]int64{"todo": 1, "done": 1}, userRes.TaskCounts)
+
+	assert.Equal(t, http.StatusNotFound, callAdmin(t, cfg.HandleAdminGetUser, http.MethodGet, "/api/admin/users/nobody", "nobody").Code)
+}
+
+func TestLastAdminIsProtected(t *testing.T) {
+	cfg, _, adminID := setupAdmin(t)
+
+	assert.Equal(t, http.StatusConflict, removeTestRole(t, cfg, adminID, api.RoleAdmin).Code)
+	assert.Equal(t, http.StatusConflict, callAdmin(t, cfg.HandleAdminDisableUser, http.MethodPost, "/api/admin/users/disable", adminID).Code)
+	assert.Equal(t, http.StatusConflict, callAdmin(t, cfg.HandleAdminDeleteUser, http.MethodDelete, "/api/admin/users", adminID).Code)
+
+	assignTestRole(t, cfg, "member", api.RoleAdmin)
+
+	assert.Equal(t, http.StatusOK, callAdmin(t, cfg.HandleAdminDisableUser, http.MethodPost, "/api/admin/users/disable", adminID).Code)
+	// the disabled admin doesn't count, so member is the last active one
+	assert.Equal(t, http.StatusConflict, removeTestRole(t, cfg, "member", api.RoleAdmin).Code)
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	rec := callAdmin(t, cfg.HandleAdminDisableUser, http.MethodPost, "/api/admin/users/disable", userID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, decodeAdminUserRes(rec).DisabledAt)
+
+	status, _ := authenticate(cfg, login.JWTToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, http.StatusUnauthorized, refreshTokens(t, cfg, login.RefreshToken).Code)
+
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = callAdmin(t, cfg.HandleAdminEnableUser, http.MethodPost, "/api/admin/users/enable", userID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeAdminUserRes(rec).DisabledAt)
+
+	c, rec = setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestDisabledUserApiKeyIsRejected(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	apiKey := decodeCreateApiKeyRes(createApiKey(t, cfg, userID, api.CreateApiKeyReq{Name: "cron", Scopes: []string{api.ScopeTasksRead}}))
+	callAdmin(t, cfg.HandleAdminDisableUser, http.MethodPost, "/api/admin/users/disable", userID)
+
+	status, _ := callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireScope(api.ScopeTasksRead))
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestAdminRevokeUserSessions(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+
+	rec := callAdmin(t, cfg.HandleAdminRevokeUserSessions, http.MethodDelete, "/api/admin/users/sessions", loggedInUserID(t, cfg))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	status, _ := authenticate(cfg, login.JWTToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+// countRows counts the rows of table matching where.
+func countRows(t *testing.T, db *sql.DB, table, where string, args ...any) int {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+where, args...).Scan(&count)
+	assert.NoError(t, err)
+
+	return count
+}
+
+func TestAdminDeleteUser(t *testing.T) {
+	cfg, _, _ := setupAdmin(t)
+
+	assert.Equal(t, http.StatusOK, callAdmin(t, cfg.HandleAdminDeleteUser, http.MethodDelete, "/api/admin/users", "member").Code)
+
+	_, err := cfg.DB.GetUserByID(context.Background(), "member")
+	assert.Error(t, err)
+}
+
+func TestAdminDeleteUserDeletesTheirData(t *testing.T) {
+	cfg, db, mailer, userID := setupSignedUpUser(t)
+	createTestUser(cfg, "owner", "owner", "owner@test.com")
+
+	login := decodeLoginRes(attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1"))
+	task := createTestTask(t, cfg, userID)
+	tag := createTestTag(t, cfg, userID, "work")
+	assert.Equal(t, http.StatusOK, attachTestTag(t, cfg, userID, task.ID, tag.ID).Code)
+	assert.Equal(t, http.StatusCreated, createTestReminder(t, cfg, userID, task.ID, `{"minutes_before":60}`).Code)
+	assert.Equal(t, http.StatusCreated, createApiKey(t, cfg, userID, api.CreateApiKeyReq{Name: "cli", Scopes: []string{api.ScopeTasksRead}}).Code)
+	enableTwoFactor(t, cfg, userID)
+	assert.Equal(t, http.StatusAccepted, forgotPassword(t, cfg, "email@test.com"))
+	assert.NotEmpty(t, mailer.sent)
+
+	shared := createTestTask(t, cfg, "owner")
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/shares", `{"email":"email@test.com"}`, "owner", shared.ID)
+	assert.NoError(t, cfg.HandleShareTask(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	tables := []string{
+		"tasks", "tags", "sessions", "refresh_tokens", "api_keys", "user_roles", "task_reminders",
+		"task_shares", "recovery_codes", "password_reset_tokens", "email_verification_tokens",
+	}
+	for _, table := range tables {
+		assert.NotZero(t, countRows(t, db, table, "user_id = ?", userID), table)
+	}
+
+	assert.Equal(t, http.StatusOK, callAdmin(t, cfg.HandleAdminDeleteUser, http.MethodDelete, "/api/admin/users/:id", userID).Code)
+
+	for _, table := range tables {
+		assert.Zero(t, countRows(t, db, table, "user_id = ?", userID), table)
+	}
+	assert.Zero(t, countRows(t, db, "task_tags", "task_id = ?", task.ID))
+	assert.Equal(t, 1, countRows(t, db, "tasks", "id = ?", shared.ID))
+
+	// whatever they were logged in with stops working
+	status, _ := authenticate(cfg, login.JWTToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, http.StatusUnauthorized, refreshTokens(t, cfg, login.RefreshToken).Code)
+}
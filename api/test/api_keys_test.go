@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func createApiKey(t *testing.T, cfg *api.ApiConfig, userID string, req api.CreateApiKeyReq) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+
+	return callAsUser(t, cfg.HandleCreateApiKey, userID, string(body))
+}
+
+func decodeCreateApiKeyRes(rec *httptest.ResponseRecorder) api.CreateApiKeyRes {
+	var apiKeyRes api.CreateApiKeyRes
+	json.Unmarshal(rec.Body.Bytes(), &apiKeyRes)
+
+	return apiKeyRes
+}
+
+// callWithAuthorization runs handler behind LoggedInMiddleware and the given route middleware.
+func callWithAuthorization(t *testing.T, cfg *api.ApiConfig, authorization string, route echo.MiddlewareFunc) (int, string) {
+	handler := cfg.LoggedInMiddleware(route(func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().Header.Get("userID"))
+	}))
+
+	c, rec := setupEcho(http.MethodGet, "/api/tasks", "")
+	c.Request().Header.Set("Authorization", authorization)
+	c.Request().Header.Set("apiKeyScopes", api.ScopeTasksWrite)
+
+	err := handler(c)
+	assert.NoError(t, err)
+
+	return rec.Code, rec.Body.String()
+}
+
+func TestApiKeyScopes(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	rec := createApiKey(t, cfg, userID, api.CreateApiKeyReq{Name: "cron", Scopes: []string{api.ScopeTasksRead}})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	apiKey := decodeCreateApiKeyRes(rec)
+	assert.Contains(t, apiKey.Key, apiKey.Prefix)
+	assert.Equal(t, []string{api.ScopeTasksRead}, apiKey.Scopes)
+
+	status, body := callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireScope(api.ScopeTasksRead))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, userID, body)
+
+	// scopes sent by the client are ignored
+	status, _ = callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireScope(api.ScopeTasksWrite))
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireSession)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = callWithAuthorization(t, cfg, "Bearer "+login.JWTToken, api.RequireScope(api.ScopeTasksWrite))
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = callWithAuthorization(t, cfg, "Bearer "+login.JWTToken, api.RequireSession)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = callWithAuthorization(t, cfg, "ApiKey tsk_unknown", api.RequireScope(api.ScopeTasksRead))
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestListAndRevokeApiKeys(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	apiKey := decodeCreateApiKeyRes(createApiKey(t, cfg, userID, api.CreateApiKeyReq{Name: "cron", Scopes: []string{api.ScopeTasksRead}}))
+
+	c, rec := setupEcho(http.MethodGet, "/api/keys", "")
+	c.Request().Header.Set("userID", userID)
+	assert.NoError(t, cfg.HandleGetApiKeys(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), apiKey.Key)
+
+	var apiKeys []api.ApiKeyRes
+	json.Unmarshal(rec.Body.Bytes(), &apiKeys)
+	assert.Len(t, apiKeys, 1)
+	assert.Equal(t, apiKey.Prefix, apiKeys[0].Prefix)
+
+	c, rec = setupEcho(http.MethodDelete, "/api/keys/"+apiKey.ID, "")
+	c.Request().Header.Set("userID", "someone-else")
+	c.SetParamNames("id")
+	c.SetParamValues(apiKey.ID)
+	assert.NoError(t, cfg.HandleRevokeApiKey(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, rec = setupEcho(http.MethodDelete, "/api/keys/"+apiKey.ID, "")
+	c.Request().Header.Set("userID", userID)
+	c.SetParamNames("id")
+	c.SetParamValues(apiKey.ID)
+	assert.NoError(t, cfg.HandleRevokeApiKey(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	status, _ := callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireScope(api.ScopeTasksRead))
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestExpiringApiKey(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	rec := createApiKey(t, cfg, userID, api.CreateApiKeyReq{Name: "cron", Scopes: []string{api.ScopeTasksRead}, ExpiresAt: past})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	soon := time.Now().Add(2 * time.Second).Format(time.RFC3339)
+	apiKey := decodeCreateApiKeyRes(createApiKey(t, cfg, userID, api.CreateApiKeyReq{Name: "cron", Scopes: []string{api.ScopeTasksRead}, ExpiresAt: soon}))
+	assert.NotEmpty(t, apiKey.ExpiresAt)
+
+	status, _ := callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireScope(api.ScopeTasksRead))
+	assert.Equal(t, http.StatusOK, status)
+
+	time.Sleep(2 * time.Second)
+
+	status, _ = callWithAuthorization(t, cfg, "ApiKey "+apiKey.Key, api.RequireScope(api.ScopeTasksRead))
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestCreateApiKeyValidation(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	for _, req := range []api.CreateApiKeyReq{
+		{Name: "", Scopes: []string{api.ScopeTasksRead}},
+		{Name: "cron"},
+		{Name: "cron", Scopes: []string{"admin"}},
+		{Name: "cron", Scopes: []string{api.ScopeTasksRead}, ExpiresAt: "tomorrow"},
+	} {
+		assert.Equal(t, http.StatusBadRequest, createApiKey(t, cfg, userID, req).Code, req)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/notify"
+	"github.com/magicznykacpur/taskin-backend/reminders"
+	"github.com/magicznykacpur/taskin-backend/sql/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHealth(t *testing.T) (*api.ApiConfig, *schema.Migrator) {
+	db, err := setupDB()
+	assert.NoError(t, err)
+
+	migrator, err := schema.NewMigrator(db)
+	assert.NoError(t, err)
+
+	cfg := &api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	cfg.Health = &api.Health{
+		DB:        db,
+		Migrator:  migrator,
+		Reminders: reminders.NewWorker(cfg.DB, map[string]notify.Notifier{}),
+		Version:   "1.2.3",
+		Commit:    "abc123",
+		StartedAt: time.Now().Add(-time.Minute),
+	}
+
+	return cfg, migrator
+}
+
+func checkHealth(t *testing.T, handler echo.HandlerFunc) (*httptest.ResponseRecorder, api.HealthRes) {
+	c, rec := setupEcho(http.MethodGet, "/readyz", "")
+	assert.NoError(t, handler(c))
+
+	var res api.HealthRes
+	json.Unmarshal(rec.Body.Bytes(), &res)
+
+	return rec, res
+}
+
+func TestHealthy(t *testing.T) {
+	cfg, _ := setupHealth(t)
+
+	for _, handler := range []echo.HandlerFunc{cfg.HandleLiveness, cfg.HandleReadiness} {
+		rec, res := checkHealth(t, handler)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, api.HealthOK, res.Status)
+		assert.Equal(t, "1.2.3", res.Version)
+		assert.Equal(t, "abc123", res.Commit)
+		assert.Equal(t, "1m0s", res.Uptime)
+		assert.Equal(t, int64(60), res.UptimeSeconds)
+		assert.Equal(t, api.HealthOK, res.Database.Status)
+		assert.Equal(t, api.HealthOK, res.Migrations.Status)
+		assert.Equal(t, res.Migrations.Latest, res.Migrations.Version)
+		assert.Equal(t, api.WorkerHealthRes{}, res.Workers["reminders"])
+	}
+}
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	cfg, _ := setupHealth(t)
+	cfg.Health.StartDraining()
+
+	rec, res := checkHealth(t, cfg.HandleReadiness)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, api.HealthDraining, res.Status)
+
+	rec, _ = checkHealth(t, cfg.HandleLiveness)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestReadinessFailsWithoutDatabase(t *testing.T) {
+	cfg, _ := setupHealth(t)
+	cfg.Health.DB.Close()
+
+	rec, res := checkHealth(t, cfg.HandleReadiness)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, api.HealthUnavailable, res.Status)
+	assert.Equal(t, api.HealthUnavailable, res.Database.Status)
+	assert.NotEmpty(t, res.Database.Error)
+
+	// liveness still reports the outage but the process doesn't need a restart
+	rec, res = checkHealth(t, cfg.HandleLiveness)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, api.HealthUnavailable, res.Status)
+}
+
+func TestReadinessFailsWithPendingMigrations(t *testing.T) {
+	cfg, migrator := setupHealth(t)
+
+	_, err := migrator.Down(context.Background())
+	assert.NoError(t, err)
+
+	rec, res := checkHealth(t, cfg.HandleReadiness)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, api.HealthUnavailable, res.Migrations.Status)
+	assert.Equal(t, res.Migrations.Latest-1, res.Migrations.Version)
+	assert.Equal(t, "database has pending migrations", res.Migrations.Error)
+}
+
+func TestReadinessReportsWorker(t *testing.T) {
+	cfg, _ := setupHealth(t)
+
+	// a cancelled worker runs once, fails and stops
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg.Health.Reminders.Run(ctx)
+
+	_, res := checkHealth(t, cfg.HandleReadiness)
+	worker := res.Workers["reminders"]
+	assert.False(t, worker.Running)
+	assert.NotEmpty(t, worker.LastRunAt)
+	assert.Contains(t, worker.LastError, "context canceled")
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func attemptLogin(t *testing.T, cfg *api.ApiConfig, email, password, ip string) *httptest.ResponseRecorder {
+	return attemptLoginForwarded(t, cfg, email, password, ip, "")
+}
+
+// attemptLoginForwarded logs in from ip with an X-Forwarded-For and X-Real-IP header claiming
+// the request came from forwardedFor.
+func attemptLoginForwarded(t *testing.T, cfg *api.ApiConfig, email, password, ip, forwardedFor string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(api.LoginReq{Email: email, Password: password})
+	c, rec := setupEcho(http.MethodPost, "/api/login", string(body))
+	c.Request().RemoteAddr = ip + ":40000"
+	if forwardedFor != "" {
+		c.Request().Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		c.Request().Header.Set(echo.HeaderXRealIP, forwardedFor)
+	}
+
+	err := cfg.HandleLoginUser(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func TestAccountLockout(t *testing.T) {
+	cfg, _, _ := setupAdmin(t)
+	mailer := setupMailer(cfg)
+	userID := loggedInUserID(t, cfg)
+	cfg.Config.LoginLockout = config.LoginLockout{LockAfter: 3, LockDuration: time.Hour, Window: time.Hour}
+
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1").Code)
+	}
+
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "email@test.com", mailer.sent[0].To)
+	assert.Equal(t, "Your account has been locked", mailer.sent[0].Subject)
+
+	// the right password doesn't help while locked, not even from another ip
+	rec := attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.2")
+	assert.Equal(t, http.StatusLocked, rec.Code)
+	assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+
+	rec = callAdmin(t, cfg.HandleAdminGetUser, http.MethodGet, "/api/admin/users/:id", userID)
+	assert.NotEmpty(t, decodeAdminUserRes(rec).LockedUntil)
+
+	rec = callAdmin(t, cfg.HandleAdminUnlockUser, http.MethodPost, "/api/admin/users/:id/unlock", userID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeAdminUserRes(rec).LockedUntil)
+
+	assert.Equal(t, http.StatusOK, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.2").Code)
+	assert.Len(t, mailer.sent, 1)
+}
+
+func TestAccountLockExpires(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	cfg.Config.LoginLockout = config.LoginLockout{LockAfter: 2, LockDuration: 50 * time.Millisecond, Window: time.Hour}
+
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	assert.Equal(t, http.StatusLocked, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1").Code)
+
+	time.Sleep(60 * time.Millisecond)
+
+	// the failures from before the lock are forgotten once it expires
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1").Code)
+}
+
+func TestSuccessfulLoginResetsFailures(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	cfg.Config.LoginLockout = config.LoginLockout{LockAfter: 3, LockDuration: time.Hour, Window: time.Hour}
+
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1").Code)
+
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1").Code)
+}
+
+func TestLoginBackoff(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	cfg.Config.LoginLockout = config.LoginLockout{BackoffAfter: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1").Code)
+
+	rec := attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+}
+
+func TestLoginBackoffDoubles(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+	cfg.Config.LoginLockout = config.LoginLockout{BackoffAfter: 1, BaseDelay: time.Minute, MaxDelay: 3 * time.Minute, Window: time.Hour}
+
+	for _, retryAfter := range []string{"60", "120", "180", "180"} {
+		assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1").Code)
+
+		rec := attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, retryAfter, rec.Header().Get("Retry-After"))
+
+		// lift the block without forgetting the failures
+		err := cfg.DB.BlockLoginAttempts(t.Context(), database.BlockLoginAttemptsParams{Scope: "account", Subject: userID})
+		assert.NoError(t, err)
+	}
+}
+
+func TestIPBackoff(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	cfg.Config.LoginLockout = config.LoginLockout{IPBackoffAfter: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "first@test.com", "wrong", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin(t, cfg, "second@test.com", "wrong", "10.0.0.1").Code)
+
+	rec := attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.2").Code)
+}
+
+func TestIPBackoffIgnoresSpoofedHeaders(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	cfg.Config.LoginLockout = config.LoginLockout{IPBackoffAfter: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+
+	assert.Equal(t, http.StatusUnauthorized, attemptLoginForwarded(t, cfg, "first@test.com", "wrong", "10.0.0.1", "1.1.1.1").Code)
+	assert.Equal(t, http.StatusUnauthorized, attemptLoginForwarded(t, cfg, "second@test.com", "wrong", "10.0.0.1", "2.2.2.2").Code)
+
+	rec := attemptLoginForwarded(t, cfg, "email@test.com", "password", "10.0.0.1", "3.3.3.3")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestSessionRecordsConnectionIP(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	rec := attemptLoginForwarded(t, cfg, "email@test.com", "password", "10.0.0.9", "1.1.1.1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	sessions, err := cfg.DB.GetActiveUserSessions(t.Context(), database.GetActiveUserSessionsParams{UserID: userID, ExpiresAt: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.9", sessions[0].IpAddress)
+
+	// refreshing from elsewhere records the new connection, not what the headers claim
+	body, _ := json.Marshal(api.RefreshTokenReq{RefreshToken: decodeLoginRes(rec).RefreshToken})
+	c, rec := setupEcho(http.MethodPost, "/api/token/refresh", string(body))
+	c.Request().RemoteAddr = "10.0.0.10:40000"
+	c.Request().Header.Set(echo.HeaderXForwardedFor, "2.2.2.2")
+	assert.NoError(t, cfg.HandleRefreshToken(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	sessions, err = cfg.DB.GetActiveUserSessions(t.Context(), database.GetActiveUserSessionsParams{UserID: userID, ExpiresAt: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.10", sessions[0].IpAddress)
+}
+
+func TestTwoFactorFailuresLockAccount(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	secret, _ := enableTwoFactor(t, cfg, loggedInUserID(t, cfg))
+	cfg.Config.LoginLockout = config.LoginLockout{LockAfter: 3, LockDuration: time.Hour, Window: time.Hour}
+
+	challengeToken := loginChallenge(t, cfg)
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, challengeToken, "000000").Code)
+	}
+
+	assert.Equal(t, http.StatusLocked, loginWithTwoFactor(t, cfg, challengeToken, totpCode(t, secret, 0)).Code)
+}
+
+func TestPasswordResetUnlocksAccount(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	mailer := setupMailer(cfg)
+	cfg.Config.LoginLockout = config.LoginLockout{LockAfter: 1, LockDuration: time.Hour, Window: time.Hour}
+
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	assert.Equal(t, http.StatusLocked, attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1").Code)
+
+	assert.Equal(t, http.StatusAccepted, forgotPassword(t, cfg, "email@test.com"))
+	assert.Equal(t, http.StatusOK, resetPassword(t, cfg, mailer.lastToken(), "new password"))
+
+	assert.Equal(t, http.StatusOK, attemptLogin(t, cfg, "email@test.com", "new password", "10.0.0.1").Code)
+}
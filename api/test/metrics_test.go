@@ -0,0 +1,34 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginMetrics(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	cfg.Metrics = metrics.New()
+	cfg.Config.LoginLockout = config.LoginLockout{LockAfter: 2, LockDuration: time.Hour, Window: time.Hour}
+
+	attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1")
+	attemptLogin(t, cfg, "nobody@test.com", "password", "10.0.0.1")
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	attemptLogin(t, cfg, "email@test.com", "wrong", "10.0.0.1")
+	attemptLogin(t, cfg, "email@test.com", "password", "10.0.0.1")
+
+	rec := httptest.NewRecorder()
+	cfg.Metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(body), `taskin_logins_total{result="success"} 1`)
+	assert.Contains(t, string(body), `taskin_logins_total{result="failure"} 3`)
+	assert.Contains(t, string(body), `taskin_logins_total{result="blocked"} 1`)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupListDB(t *testing.T) *api.ApiConfig {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+	createTestUser(&cfg, "user-2", "user two", "two@test.com")
+
+	for i := 1; i <= 5; i++ {
+		body := fmt.Sprintf(
+			`{"title":"task %d","description":"description","priority":%d,"due_until":"2030-01-0%dT12:00:00Z"}`,
+			i, i%3, 6-i,
+		)
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", body, "user-1", "")
+		err := cfg.HandleCreateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	createTestTask(t, &cfg, "user-2")
+
+	return &cfg
+}
+
+func listTasks(t *testing.T, cfg *api.ApiConfig, rawQuery string) (int, api.PageRes[api.TaskRes]) {
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks?"+rawQuery, "", "user-1", "")
+	err := cfg.HandleGetAllUsersTasks(c)
+	assert.NoError(t, err)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var page api.PageRes[api.TaskRes]
+	json.Unmarshal(resBytes, &page)
+
+	return rec.Code, page
+}
+
+func collectTitles(t *testing.T, cfg *api.ApiConfig, rawQuery string) []string {
+	titles := []string{}
+	status, page := listTasks(t, cfg, rawQuery)
+	for {
+		assert.Equal(t, http.StatusOK, status)
+		for _, task := range page.Items {
+			titles = append(titles, task.Title)
+		}
+		if page.NextCursor == "" {
+			return titles
+		}
+		status, page = listTasks(t, cfg, rawQuery+"&cursor="+page.NextCursor)
+	}
+}
+
+func TestListTasksPaginatesByDueDate(t *testing.T) {
+	cfg := setupListDB(t)
+
+	status, page := listTasks(t, cfg, "limit=2&sort=due_until&order=asc")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2, len(page.Items))
+	assert.NotEqual(t, "", page.NextCursor)
+
+	titles := collectTitles(t, cfg, "limit=2&sort=due_until&order=asc")
+	assert.Equal(t, []string{"task 5", "task 4", "task 3", "task 2", "task 1"}, titles)
+}
+
+func TestListTasksPaginatesByPriorityWithTies(t *testing.T) {
+	cfg := setupListDB(t)
+
+	titles := collectTitles(t, cfg, "limit=2&sort=priority&order=desc")
+	assert.Equal(t, 5, len(titles))
+	assert.ElementsMatch(t, []string{"task 2", "task 5"}, titles[:2])
+	assert.ElementsMatch(t, []string{"task 1", "task 4"}, titles[2:4])
+	assert.Equal(t, "task 3", titles[4])
+}
+
+func TestListTasksInvalidParams(t *testing.T) {
+	cfg := setupListDB(t)
+
+	for _, rawQuery := range []string{"limit=0", "limit=abc", "sort=title", "order=up", "cursor=not-a-cursor"} {
+		status, _ := listTasks(t, cfg, rawQuery)
+		assert.Equal(t, http.StatusBadRequest, status, rawQuery)
+	}
+
+	_, page := listTasks(t, cfg, "limit=2&sort=due_until")
+	status, _ := listTasks(t, cfg, "limit=2&sort=priority&cursor="+page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+
+	return nil
+}
+
+var tokenPattern = regexp.MustCompile(`token=([0-9a-f]{64})`)
+
+// lastToken returns the token from the link in the last email sent.
+func (m *fakeMailer) lastToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.sent) == 0 {
+		return ""
+	}
+
+	match := tokenPattern.FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+func setupMailer(cfg *api.ApiConfig) *fakeMailer {
+	mailer := &fakeMailer{}
+	cfg.Mailer = mailer
+	cfg.Config.AppURL = "https://taskin.test/"
+
+	return mailer
+}
+
+func forgotPassword(t *testing.T, cfg *api.ApiConfig, email string) int {
+	body, _ := json.Marshal(api.ForgotPasswordReq{Email: email})
+	c, rec := setupEcho(http.MethodPost, "/api/password/forgot", string(body))
+
+	err := cfg.HandleForgotPassword(c)
+	assert.NoError(t, err)
+
+	return rec.Code
+}
+
+func resetPassword(t *testing.T, cfg *api.ApiConfig, token, password string) int {
+	body, _ := json.Marshal(api.ResetPasswordReq{Token: token, Password: password})
+	c, rec := setupEcho(http.MethodPost, "/api/password/reset", string(body))
+
+	err := cfg.HandleResetPassword(c)
+	assert.NoError(t, err)
+
+	return rec.Code
+}
+
+func TestForgotPasswordSendsResetLink(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	mailer := setupMailer(cfg)
+
+	assert.Equal(t, http.StatusAccepted, forgotPassword(t, cfg, "email@test.com"))
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "email@test.com", mailer.sent[0].To)
+	assert.Contains(t, mailer.sent[0].Body, "https://taskin.test/reset-password?token=")
+
+	token := mailer.lastToken()
+	_, err := cfg.DB.GetPasswordResetTokenByHash(context.Background(), token)
+	assert.Error(t, err)
+	_, err = cfg.DB.GetPasswordResetTokenByHash(context.Background(), auth.HashToken(token))
+	assert.NoError(t, err)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	mailer := setupMailer(cfg)
+
+	assert.Equal(t, http.StatusAccepted, forgotPassword(t, cfg, "nobody@test.com"))
+	assert.Empty(t, mailer.sent)
+}
+
+func TestForgotPasswordMailerDown(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	mailer := setupMailer(cfg)
+	mailer.err = errors.New("smtp down")
+
+	c, known := setupEcho(http.MethodPost, "/api/password/forgot", `{"email":"email@test.com"}`)
+	assert.NoError(t, cfg.HandleForgotPassword(c))
+	c, unknown := setupEcho(http.MethodPost, "/api/password/forgot", `{"email":"nobody@test.com"}`)
+	assert.NoError(t, cfg.HandleForgotPassword(c))
+
+	assert.Equal(t, http.StatusAccepted, known.Code)
+	assert.Equal(t, unknown.Code, known.Code)
+	assert.Equal(t, unknown.Body.String(), known.Body.String())
+
+	// the user can ask again once the mailer is back
+	mailer.err = nil
+	assert.Equal(t, http.StatusAccepted, forgotPassword(t, cfg, "email@test.com"))
+	assert.Equal(t, http.StatusOK, resetPassword(t, cfg, mailer.lastToken(), "new password"))
+}
+
+func TestResetPassword(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+	mailer := setupMailer(cfg)
+
+	forgotPassword(t, cfg, "email@test.com")
+	token := mailer.lastToken()
+
+	assert.Equal(t, http.StatusOK, resetPassword(t, cfg, token, "new password"))
+	assert.Equal(t, http.StatusBadRequest, resetPassword(t, cfg, token, "another password"))
+
+	assert.Equal(t, http.StatusUnauthorized, refreshTokens(t, cfg, login.RefreshToken).Code)
+
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	c, rec = setupEcho(http.MethodPost, "/api/login", `{"email":"email@test.com","password":"new password"}`)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestOnlyLatestResetTokenIsValid(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	mailer := setupMailer(cfg)
+
+	forgotPassword(t, cfg, "email@test.com")
+	first := mailer.lastToken()
+	forgotPassword(t, cfg, "email@test.com")
+	second := mailer.lastToken()
+
+	assert.Equal(t, http.StatusBadRequest, resetPassword(t, cfg, first, "new password"))
+	assert.Equal(t, http.StatusOK, resetPassword(t, cfg, second, "new password"))
+}
+
+func TestExpiredResetToken(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+
+	users, err := cfg.DB.GetUsers(context.Background())
+	assert.NoError(t, err)
+
+	err = cfg.DB.CreatePasswordResetToken(context.Background(), database.CreatePasswordResetTokenParams{
+		ID:        "expired",
+		UserID:    users[0].ID,
+		TokenHash: auth.HashToken("expired-token"),
+		CreatedAt: time.Now().UTC().Add(-time.Hour),
+		ExpiresAt: time.Now().UTC().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, resetPassword(t, cfg, "expired-token", "new password"))
+	assert.Equal(t, http.StatusBadRequest, resetPassword(t, cfg, "unknown-token", "new password"))
+	assert.Equal(t, http.StatusBadRequest, resetPassword(t, cfg, "", "new password"))
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func callRateLimited(t *testing.T, limiter echo.MiddlewareFunc, configure func(c echo.Context)) *httptest.ResponseRecorder {
+	handler := limiter(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	c, rec := setupEcho(http.MethodGet, "/api/tasks/search", "")
+	configure(c)
+
+	err := handler(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func fromIP(ip string) func(c echo.Context) {
+	return func(c echo.Context) {
+		c.Request().RemoteAddr = ip + ":40000"
+	}
+}
+
+func asUser(userID string) func(c echo.Context) {
+	return func(c echo.Context) {
+		c.Request().Header.Set("userID", userID)
+	}
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	cfg := api.ApiConfig{RateLimiter: ratelimit.NewMemoryStore()}
+	limiter := cfg.RateLimitByIP("auth", ratelimit.Limit{Requests: 1, Per: time.Minute, Burst: 2})
+
+	rec := callRateLimited(t, limiter, fromIP("10.0.0.1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1;w=60", rec.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rec.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, callRateLimited(t, limiter, fromIP("10.0.0.1")).Code)
+
+	rec = callRateLimited(t, limiter, fromIP("10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, callRateLimited(t, limiter, fromIP("10.0.0.2")).Code)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	cfg := api.ApiConfig{RateLimiter: ratelimit.NewMemoryStore()}
+	limit := ratelimit.Limit{Requests: 1, Per: time.Minute, Burst: 1}
+	search := cfg.RateLimitByUser("search", limit)
+	tasks := cfg.RateLimitByUser("api", limit)
+
+	assert.Equal(t, http.StatusOK, callRateLimited(t, search, asUser("first")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, callRateLimited(t, search, asUser("first")).Code)
+
+	// other users and other groups have their own buckets
+	assert.Equal(t, http.StatusOK, callRateLimited(t, search, asUser("second")).Code)
+	assert.Equal(t, http.StatusOK, callRateLimited(t, tasks, asUser("first")).Code)
+}
+
+func TestRateLimitWithoutStore(t *testing.T) {
+	cfg := api.ApiConfig{}
+	limiter := cfg.RateLimitByIP("global", ratelimit.Limit{Requests: 1, Per: time.Minute, Burst: 1})
+
+	for range 3 {
+		rec := callRateLimited(t, limiter, fromIP("10.0.0.1"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitIgnoresSpoofedHeaders(t *testing.T) {
+	cfg := api.ApiConfig{RateLimiter: ratelimit.NewMemoryStore()}
+	limiter := cfg.RateLimitByIP("auth", ratelimit.Limit{Requests: 1, Per: time.Minute, Burst: 2})
+
+	// a client rotating the headers it sends keeps using the bucket of its connection
+	for i, spoofed := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		rec := callRateLimited(t, limiter, func(c echo.Context) {
+			fromIP("203.0.113.7")(c)
+			c.Request().Header.Set(echo.HeaderXForwardedFor, spoofed)
+			c.Request().Header.Set(echo.HeaderXRealIP, spoofed)
+		})
+
+		if i < 2 {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		}
+	}
+}
+
+func TestIPExtractorTrustedProxies(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	assert.NoError(t, err)
+	extractor := api.IPExtractor([]*net.IPNet{proxies})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:40000"
+	req.Header.Set(echo.HeaderXForwardedFor, "1.1.1.1, 203.0.113.7, 10.0.0.1")
+	// the rightmost address the trusted proxies didn't add themselves is the client
+	assert.Equal(t, "203.0.113.7", extractor(req))
+
+	// untrusted connections can't speak for anyone else
+	req.RemoteAddr = "198.51.100.1:40000"
+	assert.Equal(t, "198.51.100.1", extractor(req))
+
+	assert.Equal(t, "198.51.100.1", api.IPExtractor(nil)(req))
+}
@@ -0,0 +1,357 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+const weeklyTaskReq = `{"title":"standup notes","description":"write them","priority":1,"due_until":"2030-01-07T09:00:00Z","recurrence_rule":"RRULE:FREQ=WEEKLY;BYDAY=MO"}`
+
+func createRecurringTask(t *testing.T, cfg *api.ApiConfig, body string) api.TaskRes {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", body, "owner", "")
+
+	err := cfg.HandleCreateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	return decodeTaskRes(rec)
+}
+
+func completeTestTask(t *testing.T, cfg *api.ApiConfig, taskID string) {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/complete", "", "owner", taskID)
+
+	err := cfg.HandleCompleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func ownerTasksByDueDate(t *testing.T, cfg *api.ApiConfig) []database.Task {
+	tasks, err := cfg.DB.ListTasks(
+		context.Background(),
+		database.ListTasksParams{UserID: "owner", SortBy: "due_until", Limit: 100},
+	)
+	assert.NoError(t, err)
+
+	return tasks
+}
+
+func TestCompletingRecurringTaskSchedulesNextOccurrence(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", task.RecurrenceRule)
+	assert.NotEmpty(t, task.SeriesID)
+
+	tag := createTestTag(t, cfg, "owner", "work")
+	attachTestTag(t, cfg, "owner", task.ID, tag.ID)
+
+	completeTestTask(t, cfg, task.ID)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "2030-01-14T09:00:00Z", tasks[1].DueUntil.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, api.TaskStatusTodo, tasks[1].Status)
+	assert.Equal(t, task.SeriesID, tasks[1].SeriesID.String)
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/:id", "", "owner", tasks[1].ID)
+	err := cfg.HandleGetTaskByID(c)
+	assert.NoError(t, err)
+	next := decodeTaskRes(rec)
+	assert.Equal(t, "standup notes", next.Title)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", next.RecurrenceRule)
+	assert.Len(t, next.Tags, 1)
+
+	c, rec = setupTaskEcho(http.MethodPost, "/api/tasks/:id/reopen", "", "owner", task.ID)
+	err = cfg.HandleReopenTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	completeTestTask(t, cfg, task.ID)
+
+	assert.Len(t, ownerTasksByDueDate(t, cfg), 2)
+}
+
+func TestExhaustedRuleSchedulesNothing(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(
+		t, cfg,
+		`{"title":"once","description":"only once","priority":1,"due_until":"2030-01-07T09:00:00Z","recurrence_rule":"FREQ=DAILY;COUNT=1"}`,
+	)
+
+	completeTestTask(t, cfg, task.ID)
+
+	assert.Len(t, ownerTasksByDueDate(t, cfg), 1)
+}
+
+func TestCreateTaskWithInvalidRule(t *testing.T) {
+	cfg := setupTwoUsers()
+
+	for _, rule := range []string{"FREQ=SOMETIMES", "BYDAY=MO", "FREQ=MINUTELY", "DTSTART:20300101T000000Z\nRRULE:FREQ=DAILY"} {
+		body, _ := json.Marshal(api.CreateTaskReq{
+			Title:          "title",
+			Description:    "description",
+			Priority:       1,
+			DueUntil:       "2030-01-07T09:00:00Z",
+			RecurrenceRule: rule,
+		})
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", string(body), "owner", "")
+		err := cfg.HandleCreateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rule)
+	}
+}
+
+func TestUpdateThisOccurrence(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"title":"skip small talk","priority":-1}`, "owner", task.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "skip small talk", decodeTaskRes(rec).Title)
+
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"recurrence_rule":"FREQ=DAILY","priority":-1}`, "owner", task.ID)
+	err = cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	completeTestTask(t, cfg, task.ID)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "standup notes", tasks[1].Title)
+}
+
+func TestUpdateFutureOccurrences(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+
+	c, rec := setupTaskEcho(
+		http.MethodPut,
+		"/api/tasks/:id",
+		`{"title":"retro notes","priority":-1,"due_until":"2030-01-10T09:00:00Z","recurrence_rule":"FREQ=WEEKLY;BYDAY=TH","scope":"future"}`,
+		"owner", task.ID,
+	)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=TH", decodeTaskRes(rec).RecurrenceRule)
+
+	completeTestTask(t, cfg, task.ID)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "retro notes", tasks[1].Title)
+	assert.Equal(t, "2030-01-17T09:00:00Z", tasks[1].DueUntil.Format("2006-01-02T15:04:05Z07:00"))
+}
+
+func TestUpdateFutureOccurrencesKeepsCount(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(
+		t, cfg,
+		`{"title":"physio","description":"knee","priority":1,"due_until":"2030-01-07T09:00:00Z","recurrence_rule":"FREQ=WEEKLY;COUNT=3"}`,
+	)
+	completeTestTask(t, cfg, task.ID)
+	second := ownerTasksByDueDate(t, cfg)[1]
+
+	// moving the second of three sessions leaves two, not three more
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"priority":-1,"due_until":"2030-01-15T09:00:00Z","scope":"future"}`, "owner", second.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "FREQ=WEEKLY;UNTIL=20300122T090000Z", decodeTaskRes(rec).RecurrenceRule)
+
+	completeTestTask(t, cfg, second.ID)
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 3)
+	assert.Equal(t, "2030-01-22T09:00:00Z", tasks[2].DueUntil.Format("2006-01-02T15:04:05Z07:00"))
+
+	completeTestTask(t, cfg, tasks[2].ID)
+	assert.Len(t, ownerTasksByDueDate(t, cfg), 3)
+
+	// a new rule counts from the occurrence it's set on
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"priority":-1,"recurrence_rule":"FREQ=DAILY;COUNT=2","scope":"future"}`, "owner", tasks[2].ID)
+	err = cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "FREQ=DAILY;UNTIL=20300123T090000Z", decodeTaskRes(rec).RecurrenceRule)
+}
+
+func TestUpdateFutureOccurrencesOnlyOwner(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/shares", `{"email":"intruder@test.com"}`, "owner", task.ID)
+	assert.NoError(t, cfg.HandleShareTask(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	for _, body := range []string{
+		`{"title":"nothing to note","priority":-1,"scope":"future"}`,
+		`{"priority":-1,"recurrence_rule":"FREQ=DAILY;COUNT=1","scope":"future"}`,
+	} {
+		c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", body, "intruder", task.ID)
+		err := cfg.HandleUpdateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code, body)
+	}
+
+	// a shared task can still be edited one occurrence at a time
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"title":"notes for this week","priority":-1}`, "intruder", task.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	completeTestTask(t, cfg, task.ID)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "standup notes", tasks[1].Title)
+	assert.Equal(t, "2030-01-14T09:00:00Z", tasks[1].DueUntil.Format("2006-01-02T15:04:05Z07:00"))
+}
+
+func TestUpdateScopeValidation(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	for _, body := range []string{`{"scope":"past","priority":-1}`, `{"scope":"future","priority":-1}`} {
+		c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", body, "owner", task.ID)
+		err := cfg.HandleUpdateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"recurrence_rule":"FREQ=DAILY","priority":-1}`, "owner", task.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "FREQ=DAILY", decodeTaskRes(rec).RecurrenceRule)
+}
+
+func TestStopRecurrence(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id/recurrence", "", "owner", task.ID)
+	err := cfg.HandleStopRecurrence(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeTaskRes(rec).RecurrenceRule)
+
+	completeTestTask(t, cfg, task.ID)
+
+	assert.Len(t, ownerTasksByDueDate(t, cfg), 1)
+}
+
+func TestStopRecurrenceOnlyOwner(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+	shareTestTask(t, cfg, task.ID, "intruder@test.com")
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id/recurrence", "", "intruder", task.ID)
+	err := cfg.HandleStopRecurrence(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	completeTestTask(t, cfg, task.ID)
+
+	assert.Len(t, ownerTasksByDueDate(t, cfg), 2)
+}
+
+func TestRuleWithoutOccurrences(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+
+	for _, rule := range []string{
+		"FREQ=DAILY;COUNT=3;UNTIL=20300201T000000Z",
+		"FREQ=DAILY;UNTIL=20000101T000000Z",
+		"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30;COUNT=2",
+	} {
+		body, _ := json.Marshal(api.CreateTaskReq{
+			Title:          "title",
+			Description:    "description",
+			Priority:       1,
+			DueUntil:       "2030-01-07T09:00:00Z",
+			RecurrenceRule: rule,
+		})
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", string(body), "owner", "")
+		err := cfg.HandleCreateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rule)
+
+		body, _ = json.Marshal(api.UpdateTaskReq{Title: "changed", Priority: -1, RecurrenceRule: rule, Scope: api.RecurrenceScopeFuture})
+		c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id", string(body), "owner", task.ID)
+		err = cfg.HandleUpdateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rule)
+	}
+
+	// a rule that ends before the task's new due date is checked against that date
+	c, rec := setupTaskEcho(
+		http.MethodPut, "/api/tasks/:id",
+		`{"priority":-1,"due_until":"2030-03-01T09:00:00Z","recurrence_rule":"FREQ=DAILY;UNTIL=20300201T000000Z","scope":"future"}`,
+		"owner", task.ID,
+	)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "standup notes", tasks[0].Title)
+	assert.Equal(t, "2030-01-07T09:00:00Z", tasks[0].DueUntil.Format("2006-01-02T15:04:05Z07:00"))
+}
+
+func previewRecurrence(t *testing.T, cfg *api.ApiConfig, query url.Values) (int, api.RecurrencePreviewRes) {
+	c, rec := setupTaskEcho(http.MethodGet, "/api/recurrence/preview?"+query.Encode(), "", "owner", "")
+	err := cfg.HandlePreviewRecurrence(c)
+	assert.NoError(t, err)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var preview api.RecurrencePreviewRes
+	json.Unmarshal(resBytes, &preview)
+
+	return rec.Code, preview
+}
+
+func TestPreviewRecurrence(t *testing.T) {
+	cfg := setupTwoUsers()
+
+	status, preview := previewRecurrence(t, cfg, url.Values{
+		"rule":  {"FREQ=MONTHLY;BYMONTHDAY=1"},
+		"start": {"2030-01-15T08:00:00Z"},
+		"count": {"3"},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"2030-02-01T08:00:00Z", "2030-03-01T08:00:00Z", "2030-04-01T08:00:00Z"}, preview.Occurrences)
+
+	status, preview = previewRecurrence(t, cfg, url.Values{
+		"rule":  {"FREQ=DAILY;COUNT=2"},
+		"start": {"2030-01-15T08:00:00Z"},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, preview.Occurrences, 2)
+
+	for _, query := range []url.Values{
+		{},
+		{"rule": {"FREQ=DAILY"}, "count": {"0"}},
+		{"rule": {"FREQ=DAILY"}, "start": {"tomorrow"}},
+		{"rule": {"FREQ=NEVER"}},
+	} {
+		status, _ := previewRecurrence(t, cfg, query)
+		assert.Equal(t, http.StatusBadRequest, status, query.Encode())
+	}
+}
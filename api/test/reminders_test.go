@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/notify"
+	"github.com/magicznykacpur/taskin-backend/reminders"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Reminder
+	err  error
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, reminder notify.Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, reminder)
+
+	return nil
+}
+
+func decodeReminderRes(rec *httptest.ResponseRecorder) api.ReminderRes {
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var reminderRes api.ReminderRes
+	if err := json.Unmarshal(resBytes, &reminderRes); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
+	return reminderRes
+}
+
+func createTestReminder(t *testing.T, cfg *api.ApiConfig, userID, taskID, body string) *httptest.ResponseRecorder {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/reminders", body, userID, taskID)
+
+	err := cfg.HandleCreateReminder(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func createDueTask(t *testing.T, cfg *api.ApiConfig, dueUntil time.Time) api.TaskRes {
+	body, _ := json.Marshal(api.CreateTaskReq{
+		Title:       "pay rent",
+		Description: "transfer to landlord",
+		Priority:    1,
+		DueUntil:    dueUntil.Format(time.RFC3339),
+	})
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", string(body), "owner", "")
+
+	err := cfg.HandleCreateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	return decodeTaskRes(rec)
+}
+
+func TestCreateReminder(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	rec := createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":60}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	reminder := decodeReminderRes(rec)
+	assert.Equal(t, "2030-01-01T11:00:00Z", reminder.RemindAt)
+	assert.Equal(t, notify.ChannelEmail, reminder.Channel)
+	assert.Equal(t, reminders.StatusPending, reminder.Status)
+
+	for _, body := range []string{`{"minutes_before":60}`, `{"minutes_before":-5}`, `{"minutes_before":60,"channel":"sms"}`, `{"minutes_before`} {
+		rec := createTestReminder(t, cfg, "owner", task.ID, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	rec = createTestReminder(t, cfg, "intruder", task.ID, `{"minutes_before":60}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRemindersFollowDueDate(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+	createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":1440}`)
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"due_until":"2030-02-01T12:00:00Z","priority":-1}`, "owner", task.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodGet, "/api/tasks/:id/reminders", "", "owner", task.ID)
+	err = cfg.HandleGetTaskReminders(c)
+	assert.NoError(t, err)
+
+	var remindersRes []api.ReminderRes
+	json.Unmarshal(rec.Body.Bytes(), &remindersRes)
+	assert.Len(t, remindersRes, 1)
+	assert.Equal(t, "2030-01-31T12:00:00Z", remindersRes[0].RemindAt)
+}
+
+func TestDeleteReminder(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+	reminder := decodeReminderRes(createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":60}`))
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id/reminders/:reminderID", "", "owner", "")
+	c.SetParamNames("id", "reminderID")
+	c.SetParamValues(task.ID, reminder.ID)
+	err := cfg.HandleDeleteReminder(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodGet, "/api/tasks/:id/reminders", "", "owner", task.ID)
+	err = cfg.HandleGetTaskReminders(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
+
+func TestWorkerSendsDueRemindersOnce(t *testing.T) {
+	cfg := setupTwoUsers()
+	dueTask := createDueTask(t, cfg, time.Now().Add(30*time.Minute))
+	laterTask := createDueTask(t, cfg, time.Now().Add(48*time.Hour))
+	doneTask := createDueTask(t, cfg, time.Now().Add(30*time.Minute))
+
+	createTestReminder(t, cfg, "owner", dueTask.ID, `{"minutes_before":60}`)
+	createTestReminder(t, cfg, "owner", laterTask.ID, `{"minutes_before":60}`)
+	createTestReminder(t, cfg, "owner", doneTask.ID, `{"minutes_before":60}`)
+
+	c, _ := setupTaskEcho(http.MethodPost, "/api/tasks/:id/complete", "", "owner", doneTask.ID)
+	assert.NoError(t, cfg.HandleCompleteTask(c))
+
+	notifier := &fakeNotifier{}
+	worker := reminders.NewWorker(cfg.DB, map[string]notify.Notifier{notify.ChannelEmail: notifier})
+
+	sent, err := worker.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Len(t, notifier.sent, 1)
+	assert.Equal(t, dueTask.ID, notifier.sent[0].TaskID)
+	assert.Equal(t, "owner@test.com", notifier.sent[0].Email)
+
+	restarted := reminders.NewWorker(cfg.DB, map[string]notify.Notifier{notify.ChannelEmail: notifier})
+	sent, err = restarted.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, notifier.sent, 1)
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/:id/reminders", "", "owner", dueTask.ID)
+	assert.NoError(t, cfg.HandleGetTaskReminders(c))
+	var remindersRes []api.ReminderRes
+	json.Unmarshal(rec.Body.Bytes(), &remindersRes)
+	assert.Equal(t, reminders.StatusSent, remindersRes[0].Status)
+	assert.NotEmpty(t, remindersRes[0].SentAt)
+}
+
+func TestWorkerRetriesFailedDeliveries(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createDueTask(t, cfg, time.Now().Add(30*time.Minute))
+	createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":60,"channel":"webhook"}`)
+
+	notifier := &fakeNotifier{err: errors.New("webhook down")}
+	worker := reminders.NewWorker(cfg.DB, map[string]notify.Notifier{notify.ChannelWebhook: notifier})
+	worker.MaxAttempts = 2
+
+	for i := 0; i < 3; i++ {
+		sent, err := worker.RunOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent)
+	}
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/:id/reminders", "", "owner", task.ID)
+	assert.NoError(t, cfg.HandleGetTaskReminders(c))
+	var remindersRes []api.ReminderRes
+	json.Unmarshal(rec.Body.Bytes(), &remindersRes)
+	assert.Equal(t, reminders.StatusFailed, remindersRes[0].Status)
+	assert.Equal(t, int64(2), remindersRes[0].Attempts)
+	assert.Equal(t, "webhook down", remindersRes[0].LastError)
+}
+
+func TestRecurringOccurrenceKeepsReminders(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+	createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":30}`)
+
+	completeTestTask(t, cfg, task.ID)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 2)
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/:id/reminders", "", "owner", tasks[1].ID)
+	assert.NoError(t, cfg.HandleGetTaskReminders(c))
+	var remindersRes []api.ReminderRes
+	json.Unmarshal(rec.Body.Bytes(), &remindersRes)
+	assert.Len(t, remindersRes, 1)
+	assert.Equal(t, "2030-01-14T08:30:00Z", remindersRes[0].RemindAt)
+}
+
+func shareTestTask(t *testing.T, cfg *api.ApiConfig, taskID, email string) {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/shares", `{"email":"`+email+`"}`, "owner", taskID)
+
+	err := cfg.HandleShareTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestUnsharedTaskSendsNoReminders(t *testing.T) {
+	cfg := setupTwoUsers()
+	unshared := createDueTask(t, cfg, time.Now().Add(30*time.Minute))
+	revoked := createDueTask(t, cfg, time.Now().Add(30*time.Minute))
+
+	for _, task := range []api.TaskRes{unshared, revoked} {
+		shareTestTask(t, cfg, task.ID, "intruder@test.com")
+		assert.Equal(t, http.StatusCreated, createTestReminder(t, cfg, "intruder", task.ID, `{"minutes_before":60}`).Code)
+	}
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id/shares/:userID", "", "owner", "")
+	c.SetParamNames("id", "userID")
+	c.SetParamValues(unshared.ID, "intruder")
+	assert.NoError(t, cfg.HandleUnshareTask(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	remaining, err := cfg.DB.GetAllTaskReminders(context.Background(), unshared.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	// a share gone without its reminders, e.g. from before they were deleted with it
+	err = cfg.DB.DeleteTaskShare(
+		context.Background(),
+		database.DeleteTaskShareParams{TaskID: revoked.ID, SharedWithID: "intruder", OwnerID: "owner"},
+	)
+	assert.NoError(t, err)
+
+	notifier := &fakeNotifier{}
+	worker := reminders.NewWorker(cfg.DB, map[string]notify.Notifier{notify.ChannelEmail: notifier})
+	sent, err := worker.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Empty(t, notifier.sent)
+}
+
+func TestRecurringOccurrenceKeepsOnlyAccessibleReminders(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createRecurringTask(t, cfg, weeklyTaskReq)
+	shareTestTask(t, cfg, task.ID, "intruder@test.com")
+	createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":30}`)
+	createTestReminder(t, cfg, "intruder", task.ID, `{"minutes_before":30}`)
+
+	completeTestTask(t, cfg, task.ID)
+
+	tasks := ownerTasksByDueDate(t, cfg)
+	assert.Len(t, tasks, 2)
+
+	copied, err := cfg.DB.GetAllTaskReminders(context.Background(), tasks[1].ID)
+	assert.NoError(t, err)
+	assert.Len(t, copied, 1)
+	assert.Equal(t, "owner", copied[0].UserID)
+}
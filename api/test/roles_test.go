@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func assignTestRole(t *testing.T, cfg *api.ApiConfig, userID, role string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(api.AssignRoleReq{Role: role})
+	c, rec := setupEcho(http.MethodPost, "/api/admin/users/:id/roles", string(body))
+	c.SetParamNames("id")
+	c.SetParamValues(userID)
+
+	err := cfg.HandleAssignRole(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func removeTestRole(t *testing.T, cfg *api.ApiConfig, userID, role string) *httptest.ResponseRecorder {
+	c, rec := setupEcho(http.MethodDelete, "/api/admin/users/:id/roles/:role", "")
+	c.SetParamNames("id", "role")
+	c.SetParamValues(userID, role)
+
+	err := cfg.HandleRemoveRole(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func TestSignupAssignsUserRole(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+
+	rec := callAdmin(t, cfg.HandleAdminGetUser, http.MethodGet, "/api/admin/users/:id", loggedInUserID(t, cfg))
+	assert.Equal(t, []string{api.RoleUser}, decodeAdminUserRes(rec).Roles)
+}
+
+func TestRequirePermission(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+	authorization := "Bearer " + login.JWTToken
+
+	status, _ := callWithAuthorization(t, cfg, authorization, cfg.RequirePermission(api.PermissionUsersRead))
+	assert.Equal(t, http.StatusForbidden, status)
+
+	assert.Equal(t, http.StatusOK, assignTestRole(t, cfg, userID, api.RoleSupport).Code)
+
+	status, _ = callWithAuthorization(t, cfg, authorization, cfg.RequirePermission(api.PermissionUsersRead))
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = callWithAuthorization(t, cfg, authorization, cfg.RequirePermission(api.PermissionUsersDelete))
+	assert.Equal(t, http.StatusForbidden, status)
+
+	assert.Equal(t, http.StatusOK, assignTestRole(t, cfg, userID, api.RoleAdmin).Code)
+
+	status, _ = callWithAuthorization(t, cfg, authorization, cfg.RequirePermission(api.PermissionUsersDelete))
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestAssignAndRemoveRoles(t *testing.T) {
+	cfg, _, _ := setupAdmin(t)
+
+	assert.Equal(t, http.StatusBadRequest, assignTestRole(t, cfg, "member", "superuser").Code)
+	assert.Equal(t, http.StatusNotFound, assignTestRole(t, cfg, "nobody", api.RoleSupport).Code)
+
+	rec := assignTestRole(t, cfg, "member", api.RoleSupport)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{api.RoleSupport}, decodeAdminUserRes(rec).Roles)
+
+	// assigning a role twice is a no-op
+	assert.Equal(t, http.StatusOK, assignTestRole(t, cfg, "member", api.RoleSupport).Code)
+
+	rec = removeTestRole(t, cfg, "member", api.RoleSupport)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeAdminUserRes(rec).Roles)
+
+	assert.Equal(t, http.StatusNotFound, removeTestRole(t, cfg, "member", api.RoleSupport).Code)
+}
+
+func TestGetRoles(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+
+	c, rec := setupEcho(http.MethodGet, "/api/admin/roles", "")
+	assert.NoError(t, cfg.HandleGetRoles(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var roles []api.RoleRes
+	json.Unmarshal(rec.Body.Bytes(), &roles)
+	assert.Len(t, roles, 3)
+
+	for _, role := range roles {
+		if role.Name == api.RoleSupport {
+			assert.ElementsMatch(t, []string{api.PermissionUsersRead, api.PermissionSessionsRevoke, api.PermissionUsersUnlock}, role.Permissions)
+		}
+		if role.Name == api.RoleUser {
+			assert.Empty(t, role.Permissions)
+		}
+	}
+}
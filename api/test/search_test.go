@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSearchDB(t *testing.T) *api.ApiConfig {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+
+	tasks := []string{
+		`{"title":"buy groceries","description":"milk, eggs and bread","priority":1,"due_until":"2030-01-01T12:00:00Z"}`,
+		`{"title":"write report","description":"quarterly report for the groceries chain","priority":2,"due_until":"2030-01-01T12:00:00Z"}`,
+		`{"title":"fix bike","description":"new brake pads","priority":3,"due_until":"2030-01-01T12:00:00Z"}`,
+	}
+	for _, task := range tasks {
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", task, "user-1", "")
+		err := cfg.HandleCreateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	return &cfg
+}
+
+func search(t *testing.T, cfg *api.ApiConfig, query string) (int, []api.SearchResultRes) {
+	status, page := searchPage(t, cfg, "q="+url.QueryEscape(query))
+	return status, page.Items
+}
+
+func searchPage(t *testing.T, cfg *api.ApiConfig, rawQuery string) (int, api.PageRes[api.SearchResultRes]) {
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/search?"+rawQuery, "", "user-1", "")
+	err := cfg.HandleSearchTasks(c)
+	assert.NoError(t, err)
+
+	return rec.Code, decodeSearchResults(rec)
+}
+
+func decodeSearchResults(rec *httptest.ResponseRecorder) api.PageRes[api.SearchResultRes] {
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var page api.PageRes[api.SearchResultRes]
+	json.Unmarshal(resBytes, &page)
+
+	return page
+}
+
+func TestSearchRanksTitleMatchesFirst(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	status, results := search(t, cfg, "groceries")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, "buy groceries", results[0].Task.Title)
+	assert.Equal(t, "buy <mark>groceries</mark>", results[0].TitleHighlight)
+	assert.Contains(t, results[1].Snippet, "<mark>groceries</mark>")
+}
+
+func TestSearchQuerySyntax(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	_, results := search(t, cfg, `"brake pads"`)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "fix bike", results[0].Task.Title)
+
+	_, results = search(t, cfg, "rep*")
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "write report", results[0].Task.Title)
+
+	_, results = search(t, cfg, "groceries NOT milk")
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "write report", results[0].Task.Title)
+
+	_, results = search(t, cfg, "bike OR bread")
+	assert.Equal(t, 2, len(results))
+}
+
+func TestSearchInvalidQuery(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	status, _ := search(t, cfg, `"unterminated`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = search(t, cfg, "")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestSearchFollowsUpdates(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	_, results := search(t, cfg, "bike")
+	assert.Equal(t, 1, len(results))
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"title":"fix scooter"}`, "user-1", results[0].Task.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, results = search(t, cfg, "bike")
+	assert.Equal(t, 0, len(results))
+
+	_, results = search(t, cfg, "scooter")
+	assert.Equal(t, 1, len(results))
+}
+
+func TestSearchPagination(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	status, page := searchPage(t, cfg, "q=groceries&limit=1")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, len(page.Items))
+	assert.Equal(t, "buy groceries", page.Items[0].Task.Title)
+	assert.NotEqual(t, "", page.NextCursor)
+
+	status, page = searchPage(t, cfg, "q=groceries&limit=1&cursor="+page.NextCursor)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, len(page.Items))
+	assert.Equal(t, "write report", page.Items[0].Task.Title)
+	assert.Equal(t, "", page.NextCursor)
+}
+
+func TestSearchQuotesSpecialCharacters(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	for query, titles := range map[string][]string{
+		"brake-pads":                 {"fix bike"},
+		"^buy +bread":                {"buy groceries"},
+		"description:milk":           {},
+		`"brake ""pads"""`:           {"fix bike"},
+		"NEAR groceries":             {},
+		"(bike OR bread) NOT milk":   {"fix bike"},
+		"groceries (milk OR report)": {"buy groceries", "write report"},
+		"(milk OR report) groceries": {"buy groceries", "write report"},
+		"(bike) (brake)":             {"fix bike"},
+	} {
+		status, results := search(t, cfg, query)
+		assert.Equal(t, http.StatusOK, status, query)
+
+		found := []string{}
+		for _, result := range results {
+			found = append(found, result.Task.Title)
+		}
+		assert.ElementsMatch(t, titles, found, query)
+	}
+}
+
+func TestSearchMalformedQuery(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	for _, query := range []string{"groceries OR", "NOT milk", "bike AND OR bread", "(bike", "bike)", "()", "*", `"-"`, "AND"} {
+		status, _ := search(t, cfg, query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
+
+func TestSearchFollowsDeletes(t *testing.T) {
+	cfg := setupSearchDB(t)
+
+	_, results := search(t, cfg, "groceries")
+	assert.Equal(t, 2, len(results))
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id", "", "user-1", results[0].Task.ID)
+	err := cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, results = search(t, cfg, "groceries")
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "write report", results[0].Task.Title)
+	assert.Equal(t, "quarterly report for the <mark>groceries</mark> chain", results[0].Snippet)
+}
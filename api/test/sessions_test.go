@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func loginOnDevice(t *testing.T, cfg *api.ApiConfig, deviceName string) api.LoginRes {
+	body, _ := json.Marshal(api.LoginReq{Email: "email@test.com", Password: "password", DeviceName: deviceName})
+	c, rec := setupEcho(http.MethodPost, "/api/login", string(body))
+	c.Request().Header.Set("User-Agent", deviceName+"-agent")
+
+	err := cfg.HandleLoginUser(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	return decodeLoginRes(rec)
+}
+
+func callAsSession(t *testing.T, cfg *api.ApiConfig, handler echo.HandlerFunc, method, jwtToken, sessionID string) *httptest.ResponseRecorder {
+	c, rec := setupEcho(method, "/api/sessions", "")
+	c.Request().Header.Set("Authorization", "Bearer "+jwtToken)
+	if sessionID != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(sessionID)
+	}
+
+	err := cfg.LoggedInMiddleware(handler)(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func listSessions(t *testing.T, cfg *api.ApiConfig, jwtToken string) []api.SessionRes {
+	rec := callAsSession(t, cfg, cfg.HandleGetSessions, http.MethodGet, jwtToken, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var sessions []api.SessionRes
+	json.Unmarshal(rec.Body.Bytes(), &sessions)
+
+	return sessions
+}
+
+func TestListSessions(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	phone := loginOnDevice(t, cfg, "phone")
+
+	sessions := listSessions(t, cfg, phone.JWTToken)
+	assert.Len(t, sessions, 2)
+
+	current := 0
+	for _, session := range sessions {
+		if session.Current {
+			current++
+			assert.Equal(t, "phone", session.DeviceName)
+			assert.Equal(t, "phone-agent", session.UserAgent)
+		}
+	}
+	assert.Equal(t, 1, current)
+}
+
+func TestLogoutRevokesOnlyCurrentSession(t *testing.T) {
+	cfg, laptop := setupLoggedInUser(t)
+	phone := loginOnDevice(t, cfg, "phone")
+
+	rec := callAsSession(t, cfg, cfg.HandleLogoutUser, http.MethodPost, phone.JWTToken, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	status, _ := authenticate(cfg, phone.JWTToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, http.StatusUnauthorized, refreshTokens(t, cfg, phone.RefreshToken).Code)
+
+	status, _ = authenticate(cfg, laptop.JWTToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, refreshTokens(t, cfg, laptop.RefreshToken).Code)
+}
+
+func TestRevokeSession(t *testing.T) {
+	cfg, laptop := setupLoggedInUser(t)
+	phone := loginOnDevice(t, cfg, "phone")
+
+	var phoneSessionID string
+	for _, session := range listSessions(t, cfg, phone.JWTToken) {
+		if session.Current {
+			phoneSessionID = session.ID
+		}
+	}
+
+	rec := callAsSession(t, cfg, cfg.HandleRevokeSession, http.MethodDelete, laptop.JWTToken, phoneSessionID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = callAsSession(t, cfg, cfg.HandleRevokeSession, http.MethodDelete, laptop.JWTToken, phoneSessionID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	status, _ := authenticate(cfg, phone.JWTToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Len(t, listSessions(t, cfg, laptop.JWTToken), 1)
+}
+
+func TestLogoutEverywhere(t *testing.T) {
+	cfg, laptop := setupLoggedInUser(t)
+	phone := loginOnDevice(t, cfg, "phone")
+
+	rec := callAsSession(t, cfg, cfg.HandleLogoutAll, http.MethodPost, laptop.JWTToken, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, login := range []api.LoginRes{laptop, phone} {
+		status, _ := authenticate(cfg, login.JWTToken)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, http.StatusUnauthorized, refreshTokens(t, cfg, login.RefreshToken).Code)
+	}
+}
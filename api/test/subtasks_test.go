@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestSubtask(t *testing.T, cfg *api.ApiConfig, userID, parentID string) api.TaskRes {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/subtasks", validTaskReq, userID, parentID)
+
+	err := cfg.HandleCreateSubtask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	return decodeTaskRes(rec)
+}
+
+// root -> child -> grandchild
+func setupTaskTree(t *testing.T) (*api.ApiConfig, api.TaskRes, api.TaskRes, api.TaskRes) {
+	cfg := setupTwoUsers()
+	root := createTestTask(t, cfg, "owner")
+	child := createTestSubtask(t, cfg, "owner", root.ID)
+	grandchild := createTestSubtask(t, cfg, "owner", child.ID)
+
+	return cfg, root, child, grandchild
+}
+
+func TestGetTaskTree(t *testing.T) {
+	cfg, root, child, grandchild := setupTaskTree(t)
+	assert.Equal(t, root.ID, child.ParentID)
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/:id/tree", "", "owner", root.ID)
+	err := cfg.HandleGetTaskTree(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var tree api.TaskTreeRes
+	if err := json.Unmarshal(resBytes, &tree); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
+	assert.Equal(t, root.ID, tree.ID)
+	assert.Equal(t, 1, len(tree.Subtasks))
+	assert.Equal(t, child.ID, tree.Subtasks[0].ID)
+	assert.Equal(t, 1, len(tree.Subtasks[0].Subtasks))
+	assert.Equal(t, grandchild.ID, tree.Subtasks[0].Subtasks[0].ID)
+	assert.Equal(t, 0, len(tree.Subtasks[0].Subtasks[0].Subtasks))
+}
+
+func TestCreateSubtaskUnderForeignTask(t *testing.T) {
+	cfg := setupTwoUsers()
+	root := createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/subtasks", validTaskReq, "intruder", root.ID)
+	err := cfg.HandleCreateSubtask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMoveTaskDetectsCycles(t *testing.T) {
+	cfg, root, child, grandchild := setupTaskTree(t)
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id/parent", `{"parent_id":"`+grandchild.ID+`"}`, "owner", root.ID)
+	err := cfg.HandleMoveTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id/parent", `{"parent_id":"`+child.ID+`"}`, "owner", child.ID)
+	err = cfg.HandleMoveTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id/parent", `{"parent_id":"`+root.ID+`"}`, "owner", grandchild.ID)
+	err = cfg.HandleMoveTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, root.ID, decodeTaskRes(rec).ParentID)
+
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id/parent", `{"parent_id":""}`, "owner", grandchild.ID)
+	err = cfg.HandleMoveTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "", decodeTaskRes(rec).ParentID)
+}
+
+func TestDeleteTaskCascades(t *testing.T) {
+	cfg, root, child, grandchild := setupTaskTree(t)
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id", "", "owner", root.ID)
+	err := cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, id := range []string{root.ID, child.ID, grandchild.ID} {
+		_, err = cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: id, UserID: "owner"})
+		assert.Error(t, err)
+	}
+}
+
+func TestDeleteTaskDeletesDependents(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := &api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(cfg, "owner", "owner", "owner@test.com")
+	createTestUser(cfg, "intruder", "intruder", "intruder@test.com")
+
+	root := createTestTask(t, cfg, "owner")
+	child := createTestSubtask(t, cfg, "owner", root.ID)
+	tag := createTestTag(t, cfg, "owner", "work")
+
+	for _, task := range []api.TaskRes{root, child} {
+		assert.Equal(t, http.StatusOK, attachTestTag(t, cfg, "owner", task.ID, tag.ID).Code)
+		assert.Equal(t, http.StatusCreated, createTestReminder(t, cfg, "owner", task.ID, `{"minutes_before":60}`).Code)
+
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/shares", `{"email":"intruder@test.com"}`, "owner", task.ID)
+		assert.NoError(t, cfg.HandleShareTask(c))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id", "", "owner", root.ID)
+	assert.NoError(t, cfg.HandleDeleteTask(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, id := range []string{root.ID, child.ID} {
+		assert.Zero(t, countRows(t, db, "tasks", "id = ?", id))
+		for _, table := range []string{"task_tags", "task_reminders", "task_shares"} {
+			assert.Zero(t, countRows(t, db, table, "task_id = ?", id), table)
+		}
+	}
+	assert.Equal(t, 1, countRows(t, db, "tags", "id = ?", tag.ID))
+
+	// the database itself doesn't let subtasks outlive their parent
+	parent := createTestTask(t, cfg, "owner")
+	subtask := createTestSubtask(t, cfg, "owner", parent.ID)
+	_, err = db.Exec("DELETE FROM tasks WHERE id = ?", parent.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, countRows(t, db, "tasks", "id = ?", subtask.ID))
+}
+
+func TestDeleteTaskReparents(t *testing.T) {
+	cfg, root, child, grandchild := setupTaskTree(t)
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id?children=reparent", "", "owner", child.ID)
+	err := cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, err = cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: child.ID, UserID: "owner"})
+	assert.Error(t, err)
+
+	moved, err := cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: grandchild.ID, UserID: "owner"})
+	assert.NoError(t, err)
+	assert.Equal(t, root.ID, moved.ParentID.String)
+
+	c, rec = setupTaskEcho(http.MethodDelete, "/api/tasks/:id?children=orphan", "", "owner", root.ID)
+	err = cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDeleteTaskReparentRollsBack(t *testing.T) {
+	cfg, _, child, grandchild := setupTaskTree(t)
+
+	_, err := cfg.Conn.Exec(`CREATE TRIGGER fail_delete BEFORE DELETE ON tasks BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+	assert.NoError(t, err)
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id?children=reparent", "", "owner", child.ID)
+	err = cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	_, err = cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: child.ID, UserID: "owner"})
+	assert.NoError(t, err)
+
+	unmoved, err := cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: grandchild.ID, UserID: "owner"})
+	assert.NoError(t, err)
+	assert.Equal(t, child.ID, unmoved.ParentID.String)
+}
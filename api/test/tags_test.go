@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeTagRes(rec *httptest.ResponseRecorder) api.TagRes {
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var tagRes api.TagRes
+	if err := json.Unmarshal(resBytes, &tagRes); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
+	return tagRes
+}
+
+func createTestTag(t *testing.T, cfg *api.ApiConfig, userID, name string) api.TagRes {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tags", `{"name":"`+name+`","color":"#ff0000"}`, userID, "")
+
+	err := cfg.HandleCreateTag(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	return decodeTagRes(rec)
+}
+
+func attachTestTag(t *testing.T, cfg *api.ApiConfig, userID, taskID, tagID string) *httptest.ResponseRecorder {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/tags", `{"tag_id":"`+tagID+`"}`, userID, taskID)
+
+	err := cfg.HandleAttachTag(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func TestCreateTagValidation(t *testing.T) {
+	cfg := setupTwoUsers()
+	createTestTag(t, cfg, "owner", "work")
+
+	for _, body := range []string{`{"name":"work"}`, `{"name":""}`, `{"name":"home","color":"red"}`} {
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tags", body, "owner", "")
+		err := cfg.HandleCreateTag(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	createTestTag(t, cfg, "intruder", "work")
+}
+
+func TestUpdateAndDeleteTag(t *testing.T) {
+	cfg := setupTwoUsers()
+	tag := createTestTag(t, cfg, "owner", "work")
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tags/:id", `{"name":"office","color":"#00ff00"}`, "intruder", tag.ID)
+	err := cfg.HandleUpdateTag(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tags/:id", `{"name":"office","color":"#00ff00"}`, "owner", tag.ID)
+	err = cfg.HandleUpdateTag(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "office", decodeTagRes(rec).Name)
+
+	c, rec = setupTaskEcho(http.MethodDelete, "/api/tags/:id", "", "owner", tag.ID)
+	err = cfg.HandleDeleteTag(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	tags, err := cfg.DB.GetUsersTags(context.Background(), "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(tags))
+}
+
+func TestAttachAndDetachTag(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+	tag := createTestTag(t, cfg, "owner", "work")
+	foreignTag := createTestTag(t, cfg, "intruder", "mine")
+
+	rec := attachTestTag(t, cfg, "owner", task.ID, foreignTag.ID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = attachTestTag(t, cfg, "intruder", task.ID, foreignTag.ID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = attachTestTag(t, cfg, "owner", task.ID, tag.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	tagged := decodeTaskRes(rec)
+	assert.Equal(t, 1, len(tagged.Tags))
+	assert.Equal(t, "work", tagged.Tags[0].Name)
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id/tags/:tagID", "", "owner", "")
+	c.SetParamNames("id", "tagID")
+	c.SetParamValues(task.ID, tag.ID)
+	err := cfg.HandleDetachTag(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 0, len(decodeTaskRes(rec).Tags))
+
+	rows, err := cfg.DB.GetTagsForTasks(context.Background(), []string{task.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []database.GetTagsForTasksRow(nil), rows)
+}
+
+func TestDeleteTagDetachesItFromTasks(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := &api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(cfg, "owner", "owner", "owner@test.com")
+
+	task := createTestTask(t, cfg, "owner")
+	tag := createTestTag(t, cfg, "owner", "work")
+	kept := createTestTag(t, cfg, "owner", "home")
+	assert.Equal(t, http.StatusOK, attachTestTag(t, cfg, "owner", task.ID, tag.ID).Code)
+	assert.Equal(t, http.StatusOK, attachTestTag(t, cfg, "owner", task.ID, kept.ID).Code)
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tags/:id", "", "owner", tag.ID)
+	err = cfg.HandleDeleteTag(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Zero(t, countRows(t, db, "task_tags", "tag_id = ?", tag.ID))
+	assert.Equal(t, 1, countRows(t, db, "task_tags", "task_id = ?", task.ID))
+
+	rows, err := cfg.DB.GetTagsForTasks(context.Background(), []string{task.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "home", rows[0].Tag.Name)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupFilterDB(t *testing.T) *api.ApiConfig {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+
+	tags := map[string]api.TagRes{}
+	for _, name := range []string{"home", "work", "travel", "urgent"} {
+		tags[name] = createTestTag(t, &cfg, "user-1", name)
+	}
+
+	tasks := []string{
+		`{"title":"pay rent","description":"d","priority":5,"due_until":"2020-01-01T12:00:00Z"}`,
+		`{"title":"send invoice","description":"d","priority":3,"due_until":"2020-06-01T12:00:00Z"}`,
+		`{"title":"plan trip","description":"d","priority":1,"due_until":"2030-01-01T12:00:00+02:00"}`,
+		`{"title":"review pr","description":"d","priority":4,"due_until":"2030-06-01T12:00:00Z"}`,
+	}
+	taskTags := map[string][]string{
+		"pay rent":     {"home", "urgent"},
+		"send invoice": {"work", "urgent"},
+		"plan trip":    {"travel"},
+		"review pr":    {"work"},
+	}
+	for _, task := range tasks {
+		c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", task, "user-1", "")
+		err := cfg.HandleCreateTask(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		created := decodeTaskRes(rec)
+		for _, name := range taskTags[created.Title] {
+			attachTestTag(t, &cfg, "user-1", created.ID, tags[name].ID)
+		}
+		if created.Title == "send invoice" {
+			c, _ := setupTaskEcho(http.MethodPost, "/api/tasks/:id/complete", "", "user-1", created.ID)
+			assert.NoError(t, cfg.HandleCompleteTask(c))
+		}
+	}
+
+	return &cfg
+}
+
+func TestFilterTasksByAnyTag(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	titles := collectTitles(t, cfg, "sort=priority&order=asc&tag=work&tag=travel")
+	assert.Equal(t, []string{"plan trip", "send invoice", "review pr"}, titles)
+}
+
+func TestFilterTasksByAllTags(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	titles := collectTitles(t, cfg, "sort=priority&order=asc&tag=work&tag=urgent&tag_match=all")
+	assert.Equal(t, []string{"send invoice"}, titles)
+
+	titles = collectTitles(t, cfg, "tag=work&tag=work&tag_match=all")
+	assert.Equal(t, 2, len(titles))
+}
+
+func TestFilterTasksByPriorityRange(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	titles := collectTitles(t, cfg, "sort=priority&order=asc&priority_min=3&priority_max=4")
+	assert.Equal(t, []string{"send invoice", "review pr"}, titles)
+}
+
+func TestFilterTasksByDueDate(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	titles := collectTitles(t, cfg, "sort=due_until&order=asc&due_after=2025-01-01T00:00:00Z")
+	assert.Equal(t, []string{"plan trip", "review pr"}, titles)
+
+	titles = collectTitles(t, cfg, "sort=due_until&order=asc&due_before=2030-01-01T11:30:00%2B01:00")
+	assert.Equal(t, []string{"pay rent", "send invoice", "plan trip"}, titles)
+}
+
+func TestFilterOverdueTasks(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	titles := collectTitles(t, cfg, "overdue=true")
+	assert.Equal(t, []string{"pay rent"}, titles)
+}
+
+func TestFilterTasksByStatus(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	titles := collectTitles(t, cfg, "status=done")
+	assert.Equal(t, []string{"send invoice"}, titles)
+
+	titles = collectTitles(t, cfg, "created_after=2000-01-01T00:00:00Z&updated_before=2000-01-01T00:00:00Z")
+	assert.Equal(t, []string{}, titles)
+}
+
+func TestFilterTasksInvalidParams(t *testing.T) {
+	cfg := setupFilterDB(t)
+
+	for _, rawQuery := range []string{
+		"status=finished",
+		"priority_min=high",
+		"priority_min=5&priority_max=1",
+		"due_before=tomorrow",
+		"created_after=2020-01-01",
+		"overdue=maybe",
+		"tag=",
+		"tag=work&tag_match=some",
+	} {
+		status, _ := listTasks(t, cfg, rawQuery)
+		assert.Equal(t, http.StatusBadRequest, status, rawQuery)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTwoUsers() *api.ApiConfig {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "owner", "owner", "owner@test.com")
+	createTestUser(&cfg, "intruder", "intruder", "intruder@test.com")
+
+	return &cfg
+}
+
+func TestGetForeignTask(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/:id", "", "intruder", task.ID)
+	err := cfg.HandleGetTaskByID(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpdateForeignTask(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"title":"hijacked"}`, "intruder", task.ID)
+	err := cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	dbTask, err := cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: task.ID, UserID: "owner"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test task", dbTask.Title)
+}
+
+func TestCompleteForeignTask(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/complete", "", "intruder", task.ID)
+	err := cfg.HandleCompleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeleteForeignTask(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodDelete, "/api/tasks/:id", "", "intruder", task.ID)
+	err := cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	_, err = cfg.DB.GetTaskByID(context.Background(), database.GetTaskByIDParams{ID: task.ID, UserID: "owner"})
+	assert.NoError(t, err)
+}
+
+func TestSearchSkipsForeignTasks(t *testing.T) {
+	cfg := setupTwoUsers()
+	createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodGet, "/api/tasks/search?q=test", "", "intruder", "")
+	err := cfg.HandleSearchTasks(c)
+	assert.NoError(t, err)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var page api.PageRes[api.SearchResultRes]
+	if err := json.Unmarshal(resBytes, &page); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
+	assert.Equal(t, 0, len(page.Items))
+}
+
+func TestSharedTaskAccess(t *testing.T) {
+	cfg := setupTwoUsers()
+	task := createTestTask(t, cfg, "owner")
+
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/shares", `{"email":"intruder@test.com"}`, "intruder", task.ID)
+	err := cfg.HandleShareTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodPost, "/api/tasks/:id/shares", `{"email":"intruder@test.com"}`, "owner", task.ID)
+	err = cfg.HandleShareTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodGet, "/api/tasks/:id", "", "intruder", task.ID)
+	err = cfg.HandleGetTaskByID(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodDelete, "/api/tasks/:id", "", "intruder", task.ID)
+	err = cfg.HandleDeleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodDelete, "/api/tasks/:id/shares/:userID", "", "owner", task.ID)
+	c.SetParamNames("id", "userID")
+	c.SetParamValues(task.ID, "intruder")
+	err = cfg.HandleUnshareTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	c, rec = setupTaskEcho(http.MethodGet, "/api/tasks/:id", "", "intruder", task.ID)
+	err = cfg.HandleGetTaskByID(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
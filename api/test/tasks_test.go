@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+const validTaskReq = `{"title":"test task","description":"test description","priority":1,"due_until":"2030-01-01T12:00:00Z"}`
+
+func createTestUser(cfg *api.ApiConfig, id, username, email string) {
+	err := cfg.DB.CreateUser(context.Background(), database.CreateUserParams{
+		ID:             id,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Email:          email,
+		Username:       username,
+		HashedPassword: "hash",
+	})
+	if err != nil {
+		log.Fatalf("couldnt create user: %v", err)
+	}
+}
+
+func setupTaskEcho(method, path, body, userID, taskID string) (echo.Context, *httptest.ResponseRecorder) {
+	c, rec := setupEcho(method, path, body)
+	c.Request().Header.Set("userID", userID)
+	if taskID != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(taskID)
+	}
+
+	return c, rec
+}
+
+func decodeTaskRes(rec *httptest.ResponseRecorder) api.TaskRes {
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var taskRes api.TaskRes
+	if err := json.Unmarshal(resBytes, &taskRes); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
+	return taskRes
+}
+
+func createTestTask(t *testing.T, cfg *api.ApiConfig, userID string) api.TaskRes {
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks", validTaskReq, userID, "")
+
+	err := cfg.HandleCreateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	return decodeTaskRes(rec)
+}
+
+func TestCreatedTaskIsTodo(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+
+	task := createTestTask(t, &cfg, "user-1")
+
+	assert.Equal(t, api.TaskStatusTodo, task.Status)
+	assert.Equal(t, "", task.CompletedAt)
+}
+
+func TestCompleteAndReopenTask(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+	task := createTestTask(t, &cfg, "user-1")
+
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/complete", "", "user-1", task.ID)
+	err = cfg.HandleCompleteTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	completed := decodeTaskRes(rec)
+	assert.Equal(t, api.TaskStatusDone, completed.Status)
+	assert.NotEqual(t, "", completed.CompletedAt)
+
+	c, rec = setupTaskEcho(http.MethodPost, "/api/tasks/:id/reopen", "", "user-1", task.ID)
+	err = cfg.HandleReopenTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	reopened := decodeTaskRes(rec)
+	assert.Equal(t, api.TaskStatusTodo, reopened.Status)
+	assert.Equal(t, "", reopened.CompletedAt)
+}
+
+func TestReopenOpenTask(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+	task := createTestTask(t, &cfg, "user-1")
+
+	c, rec := setupTaskEcho(http.MethodPost, "/api/tasks/:id/reopen", "", "user-1", task.ID)
+	err = cfg.HandleReopenTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUpdateTaskStatus(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	createTestUser(&cfg, "user-1", "user one", "one@test.com")
+	task := createTestTask(t, &cfg, "user-1")
+
+	c, rec := setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"status":"in_progress"}`, "user-1", task.ID)
+	err = cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	updated := decodeTaskRes(rec)
+	assert.Equal(t, api.TaskStatusInProgress, updated.Status)
+	assert.Equal(t, task.DueUntil, updated.DueUntil)
+
+	c, rec = setupTaskEcho(http.MethodPut, "/api/tasks/:id", `{"status":"finished"}`, "user-1", task.ID)
+	err = cfg.HandleUpdateTask(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeLoginRes(rec *httptest.ResponseRecorder) api.LoginRes {
+	res := rec.Result()
+	defer res.Body.Close()
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	var loginRes api.LoginRes
+	if err := json.Unmarshal(resBytes, &loginRes); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
+	return loginRes
+}
+
+func setupLoggedInUser(t *testing.T) (*api.ApiConfig, api.LoginRes) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+
+	c, rec := setupEcho(http.MethodPost, "/api/signup", validUserReq)
+	err = cfg.HandleCreateUser(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	c, rec = setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	err = cfg.HandleLoginUser(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	return &cfg, decodeLoginRes(rec)
+}
+
+func refreshTokens(t *testing.T, cfg *api.ApiConfig, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(api.RefreshTokenReq{RefreshToken: refreshToken})
+	c, rec := setupEcho(http.MethodPost, "/api/token/refresh", string(body))
+
+	err := cfg.HandleRefreshToken(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func TestRefreshTokensAreStoredHashed(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+
+	_, err := cfg.DB.GetRefreshTokenByHash(context.Background(), login.RefreshToken)
+	assert.Error(t, err)
+
+	stored, err := cfg.DB.GetRefreshTokenByHash(context.Background(), auth.HashRefreshToken(login.RefreshToken))
+	assert.NoError(t, err)
+	assert.Equal(t, auth.HashRefreshToken(login.RefreshToken), stored.TokenHash)
+}
+
+func TestLoginIssuesNewRefreshToken(t *testing.T) {
+	cfg, first := setupLoggedInUser(t)
+
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	err := cfg.HandleLoginUser(c)
+	assert.NoError(t, err)
+	second := decodeLoginRes(rec)
+
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+
+	rec := refreshTokens(t, cfg, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rotated := decodeLoginRes(rec)
+	assert.NotEmpty(t, rotated.JWTToken)
+	assert.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+
+	rec = refreshTokens(t, cfg, rotated.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	err := cfg.HandleLoginUser(c)
+	assert.NoError(t, err)
+	otherDevice := decodeLoginRes(rec)
+
+	rotated := decodeLoginRes(refreshTokens(t, cfg, login.RefreshToken))
+
+	rec = refreshTokens(t, cfg, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = refreshTokens(t, cfg, rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = refreshTokens(t, cfg, otherDevice.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRefreshTokenInvalid(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+
+	rec := refreshTokens(t, cfg, "not-a-refresh-token")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	c, rec := setupEcho(http.MethodPost, "/api/token/refresh", `{}`)
+	err := cfg.HandleRefreshToken(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func authenticate(cfg *api.ApiConfig, jwtToken string) (int, string) {
+	handler := cfg.LoggedInMiddleware(func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().Header.Get("userID"))
+	})
+
+	c, rec := setupEcho(http.MethodGet, "/api/me", "")
+	if jwtToken != "" {
+		c.Request().Header.Set("Authorization", "Bearer "+jwtToken)
+	}
+	c.Request().Header.Set("userID", "spoofed")
+
+	if err := handler(c); err != nil {
+		log.Fatalf("middleware returned error: %v", err)
+	}
+
+	return rec.Code, rec.Body.String()
+}
+
+func TestLoggedInMiddlewareRequiresValidJWT(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+
+	users, err := cfg.DB.GetUsers(context.Background())
+	assert.NoError(t, err)
+
+	status, body := authenticate(cfg, login.JWTToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, users[0].ID, body)
+
+	claims, err := auth.ValidateSessionJWTToken(login.JWTToken, "test-secret")
+	assert.NoError(t, err)
+
+	expired, err := auth.GenerateSessionJWTToken(users[0].ID, claims.SessionID, "test-secret", -time.Minute)
+	assert.NoError(t, err)
+	withoutSession, err := auth.GenerateJWTToken(users[0].ID, "test-secret", time.Minute)
+	assert.NoError(t, err)
+	foreignSecret, err := auth.GenerateSessionJWTToken(users[0].ID, claims.SessionID, "other-secret", time.Minute)
+	assert.NoError(t, err)
+
+	for _, token := range []string{"", expired, withoutSession, foreignSecret} {
+		status, _ := authenticate(cfg, token)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func callAsUser(t *testing.T, handler echo.HandlerFunc, userID, body string) *httptest.ResponseRecorder {
+	c, rec := setupEcho(http.MethodPost, "/api/2fa", body)
+	c.Request().Header.Set("userID", userID)
+
+	err := handler(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+// totpCode returns a valid code offset steps away from now, every step can only be used once.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+offset)
+	assert.NoError(t, err)
+
+	return code
+}
+
+func enableTwoFactor(t *testing.T, cfg *api.ApiConfig, userID string) (string, []string) {
+	rec := callAsUser(t, cfg.HandleSetupTwoFactor, userID, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var setupRes api.TwoFactorSetupRes
+	json.Unmarshal(rec.Body.Bytes(), &setupRes)
+	assert.Contains(t, setupRes.OTPAuthURI, "secret="+setupRes.Secret)
+
+	body, _ := json.Marshal(api.TwoFactorCodeReq{Code: totpCode(t, setupRes.Secret, -1)})
+	rec = callAsUser(t, cfg.HandleConfirmTwoFactor, userID, string(body))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var codesRes api.RecoveryCodesRes
+	json.Unmarshal(rec.Body.Bytes(), &codesRes)
+
+	return setupRes.Secret, codesRes.RecoveryCodes
+}
+
+func loginWithTwoFactor(t *testing.T, cfg *api.ApiConfig, challengeToken, code string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(api.LoginTwoFactorReq{ChallengeToken: challengeToken, Code: code})
+	c, rec := setupEcho(http.MethodPost, "/api/login/2fa", string(body))
+
+	err := cfg.HandleLoginTwoFactor(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func loginChallenge(t *testing.T, cfg *api.ApiConfig) string {
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	loginRes := decodeLoginRes(rec)
+	assert.True(t, loginRes.TwoFactorRequired)
+	assert.Empty(t, loginRes.JWTToken)
+	assert.Empty(t, loginRes.RefreshToken)
+
+	return loginRes.ChallengeToken
+}
+
+func loggedInUserID(t *testing.T, cfg *api.ApiConfig) string {
+	users, err := cfg.DB.GetUsers(context.Background())
+	assert.NoError(t, err)
+
+	return users[0].ID
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	secret, recoveryCodes := enableTwoFactor(t, cfg, userID)
+	assert.Len(t, recoveryCodes, 10)
+
+	challengeToken := loginChallenge(t, cfg)
+
+	status, _ := authenticate(cfg, challengeToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, challengeToken, "000000").Code)
+	// the code used to confirm the setup can't be replayed
+	assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, challengeToken, totpCode(t, secret, -1)).Code)
+
+	rec := loginWithTwoFactor(t, cfg, challengeToken, totpCode(t, secret, 0))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	status, body := authenticate(cfg, decodeLoginRes(rec).JWTToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, userID, body)
+}
+
+func TestTwoFactorRecoveryCodeIsSingleUse(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+
+	_, recoveryCodes := enableTwoFactor(t, cfg, loggedInUserID(t, cfg))
+
+	challengeToken := loginChallenge(t, cfg)
+	assert.Equal(t, http.StatusOK, loginWithTwoFactor(t, cfg, challengeToken, recoveryCodes[0]).Code)
+	assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, challengeToken, recoveryCodes[0]).Code)
+}
+
+func TestTwoFactorChallengeTokenRequired(t *testing.T) {
+	cfg, login := setupLoggedInUser(t)
+
+	secret, _ := enableTwoFactor(t, cfg, loggedInUserID(t, cfg))
+
+	assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, login.JWTToken, totpCode(t, secret, 0)).Code)
+	assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, "invalid", totpCode(t, secret, 0)).Code)
+}
+
+func TestConfirmTwoFactorRequiresValidCode(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	body, _ := json.Marshal(api.TwoFactorCodeReq{Code: "123456"})
+	assert.Equal(t, http.StatusBadRequest, callAsUser(t, cfg.HandleConfirmTwoFactor, userID, string(body)).Code)
+
+	assert.Equal(t, http.StatusOK, callAsUser(t, cfg.HandleSetupTwoFactor, userID, "").Code)
+	assert.Equal(t, http.StatusBadRequest, callAsUser(t, cfg.HandleConfirmTwoFactor, userID, string(body)).Code)
+
+	// without a confirmed setup the login doesn't ask for a code
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+	assert.NotEmpty(t, decodeLoginRes(rec).JWTToken)
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	secret, _ := enableTwoFactor(t, cfg, userID)
+
+	body, _ := json.Marshal(api.DisableTwoFactorReq{Password: "wrong", Code: totpCode(t, secret, 0)})
+	assert.Equal(t, http.StatusUnauthorized, callAsUser(t, cfg.HandleDisableTwoFactor, userID, string(body)).Code)
+
+	body, _ = json.Marshal(api.DisableTwoFactorReq{Password: "password", Code: totpCode(t, secret, 0)})
+	assert.Equal(t, http.StatusOK, callAsUser(t, cfg.HandleDisableTwoFactor, userID, string(body)).Code)
+
+	c, rec := setupEcho(http.MethodPost, "/api/login", validLoginReq)
+	assert.NoError(t, cfg.HandleLoginUser(c))
+
+	loginRes := decodeLoginRes(rec)
+	assert.False(t, loginRes.TwoFactorRequired)
+	assert.NotEmpty(t, loginRes.JWTToken)
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	cfg, _ := setupLoggedInUser(t)
+	userID := loggedInUserID(t, cfg)
+
+	secret, oldCodes := enableTwoFactor(t, cfg, userID)
+
+	body, _ := json.Marshal(api.TwoFactorCodeReq{Code: totpCode(t, secret, 0)})
+	rec := callAsUser(t, cfg.HandleRegenerateRecoveryCodes, userID, string(body))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var codesRes api.RecoveryCodesRes
+	json.Unmarshal(rec.Body.Bytes(), &codesRes)
+	assert.Len(t, codesRes.RecoveryCodes, 10)
+
+	challengeToken := loginChallenge(t, cfg)
+	assert.Equal(t, http.StatusUnauthorized, loginWithTwoFactor(t, cfg, challengeToken, oldCodes[0]).Code)
+	assert.Equal(t, http.StatusOK, loginWithTwoFactor(t, cfg, challengeToken, codesRes.RecoveryCodes[0]).Code)
+}
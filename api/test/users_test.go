@@ -14,7 +14,9 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/config"
 	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/sql/schema"
 	"github.com/stretchr/testify/assert"
 	_ "modernc.org/sqlite"
 )
@@ -26,34 +28,23 @@ var (
 	malformedUserReq = `{"username`
 )
 
-const createUsersTable = `CREATE TABLE users (
-    id TEXT NOT NULL PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
-    username TEXT NOT NULL UNIQUE,
-	email TEXT NOT NULL UNIQUE,
-    hashed_password TEXT NOT NULL
-);`
-
-const createRefreshTokensTable = `CREATE TABLE refresh_tokens (
-    user_id TEXT NOT NULL,
-    token TEXT NOT NULL,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
-    is_revoked INTEGER NOT NULL,
-    expires_at TIMESTAMP NOT NULL
-);`
+var testConfig = config.Config{Port: 42069, DBString: ":memory:", JWTSecret: "test-secret"}
 
+// setupDB returns an in memory database with every migration applied. It's limited to a single
+// connection, every connection to ":memory:" would get a database of its own.
 func setupDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite", ":memory:")
+	db, err := sql.Open("sqlite", testConfig.DSN())
 	if err != nil {
 		return nil, err
 	}
-	_, err = db.ExecContext(context.Background(), createUsersTable)
+	db.SetMaxOpenConns(1)
+
+	migrator, err := schema.NewMigrator(db)
 	if err != nil {
 		return nil, err
 	}
-	_, err = db.ExecContext(context.Background(), createRefreshTokensTable)
+
+	_, err = migrator.Up(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +54,7 @@ func setupDB() (*sql.DB, error) {
 
 func setupEcho(method, path, body string) (echo.Context, *httptest.ResponseRecorder) {
 	e := echo.New()
+	e.IPExtractor = api.IPExtractor(nil)
 	req := httptest.NewRequest(method, path, strings.NewReader(body))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -77,7 +69,7 @@ func TestValidUserReq(t *testing.T) {
 		log.Fatalf("coudlnt create database: %v", err)
 	}
 
-	cfg := api.ApiConfig{Port: ":42069", DB: database.New(db)}
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
 
 	err = cfg.HandleCreateUser(c)
 	assert.NoError(t, err)
@@ -113,7 +105,7 @@ func TestInvalidUserReq(t *testing.T) {
 		log.Fatalf("coudlnt create database: %v", err)
 	}
 
-	cfg := api.ApiConfig{Port: ":42069", DB: database.New(db)}
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
 	err = cfg.HandleCreateUser(c)
 	assert.NoError(t, err)
 
@@ -139,7 +131,7 @@ func TestMalformedRequestBody(t *testing.T) {
 		log.Fatalf("coudlnt create database: %v", err)
 	}
 
-	cfg := api.ApiConfig{Port: ":42069", DB: database.New(db)}
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
 	err = cfg.HandleCreateUser(c)
 	assert.NoError(t, err)
 
@@ -165,7 +157,7 @@ func TestUniqueUser(t *testing.T) {
 		log.Fatalf("coudlnt create database: %v", err)
 	}
 
-	cfg := api.ApiConfig{Port: ":42069", DB: database.New(db)}
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
 	err = cfg.HandleCreateUser(c)
 	assert.NoError(t, err)
 
@@ -208,6 +200,25 @@ func TestUniqueUser(t *testing.T) {
 		log.Fatalf("couldnt unmarshall res body: %v", err)
 	}
 
+	assert.Equal(t, "user with that username already exists", errorRes.ErrorMessage)
+
+	c, rec = setupEcho(http.MethodPost, "/api/users", `{"username":"other user","password":"password","email":"email@test.com"}`)
+
+	err = cfg.HandleCreateUser(c)
+	assert.NoError(t, err)
+
+	res = rec.Result()
+	defer res.Body.Close()
+	resBytes, err = io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatalf("couldnt read res body bytes: %v", err)
+	}
+
+	errorRes = api.ErrorResponse{}
+	if err := json.Unmarshal(resBytes, &errorRes); err != nil {
+		log.Fatalf("couldnt unmarshall res body: %v", err)
+	}
+
 	assert.Equal(t, "user with that email already exists", errorRes.ErrorMessage)
 }
 
@@ -219,7 +230,7 @@ func TestLoginUser(t *testing.T) {
 		log.Fatalf("coudlnt create database: %v", err)
 	}
 
-	cfg := api.ApiConfig{Port: ":42069", DB: database.New(db)}
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
 
 	err = cfg.HandleCreateUser(c)
 	assert.NoError(t, err)
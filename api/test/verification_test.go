@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSignedUpUser(t *testing.T) (*api.ApiConfig, *sql.DB, *fakeMailer, string) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := &api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+	mailer := setupMailer(cfg)
+
+	c, rec := setupEcho(http.MethodPost, "/api/signup", validUserReq)
+	err = cfg.HandleCreateUser(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	user, err := cfg.DB.GetUserByEmail(context.Background(), "email@test.com")
+	assert.NoError(t, err)
+
+	return cfg, db, mailer, user.ID
+}
+
+func verifyEmail(t *testing.T, cfg *api.ApiConfig, token string) *httptest.ResponseRecorder {
+	c, rec := setupEcho(http.MethodGet, "/api/verify-email?token="+token, "")
+
+	err := cfg.HandleVerifyEmail(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func updateEmail(t *testing.T, cfg *api.ApiConfig, userID, email string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(api.UpdateUserReq{Email: email})
+	c, rec := setupEcho(http.MethodPut, "/api/users", string(body))
+	c.Request().Header.Set("userID", userID)
+
+	err := cfg.HandleUpdateUser(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func resendVerification(t *testing.T, cfg *api.ApiConfig, userID string) *httptest.ResponseRecorder {
+	c, rec := setupEcho(http.MethodPost, "/api/verify-email/resend", "")
+	c.Request().Header.Set("userID", userID)
+
+	err := cfg.HandleResendVerification(c)
+	assert.NoError(t, err)
+
+	return rec
+}
+
+func decodeUserRes(rec *httptest.ResponseRecorder) api.UserRes {
+	var userRes api.UserRes
+	json.Unmarshal(rec.Body.Bytes(), &userRes)
+
+	return userRes
+}
+
+func TestSignupSendsVerificationEmail(t *testing.T) {
+	cfg, _, mailer, _ := setupSignedUpUser(t)
+
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "email@test.com", mailer.sent[0].To)
+	assert.Contains(t, mailer.sent[0].Body, "https://taskin.test/verify-email?token=")
+
+	token := mailer.lastToken()
+	rec := verifyEmail(t, cfg, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	userRes := decodeUserRes(rec)
+	assert.True(t, userRes.EmailVerified)
+	assert.Equal(t, "email@test.com", userRes.Email)
+
+	assert.Equal(t, http.StatusBadRequest, verifyEmail(t, cfg, token).Code)
+	assert.Equal(t, http.StatusBadRequest, verifyEmail(t, cfg, "unknown-token").Code)
+	assert.Equal(t, http.StatusBadRequest, verifyEmail(t, cfg, "").Code)
+}
+
+func TestSignupWithoutMailer(t *testing.T) {
+	db, err := setupDB()
+	if err != nil {
+		log.Fatalf("coudlnt create database: %v", err)
+	}
+
+	cfg := api.ApiConfig{Config: testConfig, DB: database.New(db), Conn: db}
+
+	c, rec := setupEcho(http.MethodPost, "/api/signup", validUserReq)
+	err = cfg.HandleCreateUser(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestEmailChangeIsPendingUntilVerified(t *testing.T) {
+	cfg, _, mailer, userID := setupSignedUpUser(t)
+
+	rec := updateEmail(t, cfg, userID, "new@test.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	userRes := decodeUserRes(rec)
+	assert.Equal(t, "email@test.com", userRes.Email)
+	assert.Equal(t, "new@test.com", userRes.PendingEmail)
+
+	assert.Len(t, mailer.sent, 2)
+	assert.Equal(t, "new@test.com", mailer.sent[1].To)
+
+	rec = verifyEmail(t, cfg, mailer.lastToken())
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	userRes = decodeUserRes(rec)
+	assert.Equal(t, "new@test.com", userRes.Email)
+	assert.Empty(t, userRes.PendingEmail)
+	assert.True(t, userRes.EmailVerified)
+
+	_, err := cfg.DB.GetUserByEmail(context.Background(), "email@test.com")
+	assert.Error(t, err)
+}
+
+func TestEmailChangeInvalidatesEarlierLinks(t *testing.T) {
+	cfg, _, mailer, userID := setupSignedUpUser(t)
+	signupToken := mailer.lastToken()
+
+	updateEmail(t, cfg, userID, "new@test.com")
+
+	assert.Equal(t, http.StatusBadRequest, verifyEmail(t, cfg, signupToken).Code)
+}
+
+func TestCancelPendingEmailChange(t *testing.T) {
+	cfg, _, mailer, userID := setupSignedUpUser(t)
+
+	updateEmail(t, cfg, userID, "new@test.com")
+	pendingToken := mailer.lastToken()
+
+	rec := updateEmail(t, cfg, userID, "email@test.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeUserRes(rec).PendingEmail)
+
+	assert.Equal(t, http.StatusBadRequest, verifyEmail(t, cfg, pendingToken).Code)
+}
+
+func TestEmailChangeToTakenEmail(t *testing.T) {
+	cfg, _, _, userID := setupSignedUpUser(t)
+
+	c, rec := setupEcho(http.MethodPost, "/api/signup", `{"username":"other","password":"password","email":"other@test.com"}`)
+	assert.NoError(t, cfg.HandleCreateUser(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	assert.Equal(t, http.StatusBadRequest, updateEmail(t, cfg, userID, "other@test.com").Code)
+}
+
+func TestResendVerificationIsRateLimited(t *testing.T) {
+	cfg, db, mailer, userID := setupSignedUpUser(t)
+
+	rec := resendVerification(t, cfg, userID)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Len(t, mailer.sent, 1)
+
+	_, err := db.Exec("UPDATE email_verification_tokens SET created_at = datetime('now', '-2 minutes')")
+	assert.NoError(t, err)
+
+	rec = resendVerification(t, cfg, userID)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mailer.sent, 2)
+
+	assert.Equal(t, http.StatusOK, verifyEmail(t, cfg, mailer.lastToken()).Code)
+	assert.Equal(t, http.StatusBadRequest, resendVerification(t, cfg, userID).Code)
+}
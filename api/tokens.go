@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+const refreshTokenLifetime = time.Hour * 24 * 31
+
+// issueRefreshToken creates a refresh token in familyID and returns it in plaintext,
+// only its hash is stored. Every login starts a new family (a session), every refresh
+// continues one.
+func (cfg *ApiConfig) issueRefreshToken(ctx context.Context, userID, familyID string) (string, error) {
+	refreshToken, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.DB.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: auth.HashRefreshToken(refreshToken),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(refreshTokenLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// HandleRefreshToken exchanges a refresh token for a new jwt and refresh token pair. Each
+// refresh token can be used once, presenting a consumed one again means it leaked, so the
+// whole family and its session are revoked and the user has to log in again.
+func (cfg *ApiConfig) HandleRefreshToken(c echo.Context) error {
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var refreshTokenReq RefreshTokenReq
+	if err := json.Unmarshal(requestBytes, &refreshTokenReq); err != nil || refreshTokenReq.RefreshToken == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	refreshToken, err := cfg.DB.GetRefreshTokenByHash(req.Context(), auth.HashRefreshToken(refreshTokenReq.RefreshToken))
+	if err != nil {
+		return respondWithError(c, http.StatusUnauthorized, "invalid refresh token")
+	}
+
+	if refreshToken.IsRevoked == 1 || refreshToken.ExpiresAt.Before(time.Now()) {
+		return respondWithError(c, http.StatusUnauthorized, "invalid refresh token")
+	}
+
+	session, err := cfg.DB.GetSessionByID(req.Context(), refreshToken.FamilyID)
+	if err != nil || session.RevokedAt.Valid {
+		return respondWithError(c, http.StatusUnauthorized, "invalid refresh token")
+	}
+
+	if active, err := cfg.checkUserActive(c, refreshToken.UserID); !active {
+		return err
+	}
+
+	consumed, err := cfg.DB.ConsumeRefreshToken(
+		req.Context(),
+		database.ConsumeRefreshTokenParams{
+			ConsumedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt:  time.Now().UTC(),
+			ID:         refreshToken.ID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt consume refresh token")
+	}
+
+	if consumed == 0 {
+		if _, err := cfg.revokeSession(c, session.ID, session.UserID); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, "couldnt revoke session")
+		}
+
+		return respondWithError(c, http.StatusUnauthorized, "refresh token reuse detected, please log in again")
+	}
+
+	newRefreshToken, err := cfg.issueRefreshToken(req.Context(), refreshToken.UserID, refreshToken.FamilyID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt create refresh token")
+	}
+
+	err = cfg.DB.RefreshSession(
+		req.Context(),
+		database.RefreshSessionParams{
+			LastUsedAt: time.Now().UTC(),
+			ExpiresAt:  time.Now().UTC().Add(refreshTokenLifetime),
+			UserAgent:  req.UserAgent(),
+			IpAddress:  c.RealIP(),
+			ID:         session.ID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt update session")
+	}
+
+	jwtToken, err := auth.GenerateSessionJWTToken(refreshToken.UserID, session.ID, cfg.Config.JWTSecret, time.Hour)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt generate jwt token")
+	}
+
+	return c.JSON(http.StatusOK, LoginRes{JWTToken: jwtToken, RefreshToken: newRefreshToken})
+}
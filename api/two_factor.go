@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/metrics"
+)
+
+const (
+	totpIssuer                 = "Taskin"
+	recoveryCodeCount          = 10
+	twoFactorChallengeLifetime = 5 * time.Minute
+)
+
+// verifySecondFactor accepts either a TOTP code or one of the user's recovery codes, both
+// of them only once.
+func (cfg *ApiConfig) verifySecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if step, ok := auth.ValidateTOTPCode(user.TotpSecret.String, code, time.Now(), user.TotpLastStep); ok {
+		used, err := cfg.DB.UseTOTPStep(ctx, database.UseTOTPStepParams{Step: step, ID: user.ID})
+		return used == 1, err
+	}
+
+	used, err := cfg.DB.UseRecoveryCode(
+		ctx,
+		database.UseRecoveryCodeParams{
+			UsedAt:   sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UserID:   user.ID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		},
+	)
+
+	return used == 1, err
+}
+
+// replaceRecoveryCodes stores a fresh set of recovery codes for the user, the previous ones
+// stop working. Only the hashes are kept, so the codes are returned to be shown once.
+func (cfg *ApiConfig) replaceRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.DB.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		err := cfg.DB.CreateRecoveryCode(
+			ctx,
+			database.CreateRecoveryCodeParams{
+				ID:        uuid.NewString(),
+				UserID:    userID,
+				CodeHash:  auth.HashToken(code),
+				CreatedAt: time.Now().UTC(),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+type TwoFactorSetupRes struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+func (cfg *ApiConfig) HandleSetupTwoFactor(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	if user.TotpEnabledAt.Valid {
+		return respondWithError(c, http.StatusBadRequest, "two factor authentication already enabled")
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt generate totp secret")
+	}
+
+	err = cfg.DB.SetTOTPSecret(
+		c.Request().Context(),
+		database.SetTOTPSecretParams{TotpSecret: sql.NullString{String: secret, Valid: true}, UpdatedAt: time.Now(), ID: user.ID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt save totp secret: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, TwoFactorSetupRes{Secret: secret, OTPAuthURI: auth.TOTPURI(totpIssuer, user.Email, secret)})
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// HandleConfirmTwoFactor turns two factor authentication on once the user proves their
+// authenticator app produces valid codes for the secret from setup.
+func (cfg *ApiConfig) HandleConfirmTwoFactor(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var codeReq TwoFactorCodeReq
+	if err := json.Unmarshal(requestBytes, &codeReq); err != nil || codeReq.Code == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	user, err := cfg.DB.GetUserByID(req.Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	if user.TotpEnabledAt.Valid {
+		return respondWithError(c, http.StatusBadRequest, "two factor authentication already enabled")
+	}
+	if !user.TotpSecret.Valid {
+		return respondWithError(c, http.StatusBadRequest, "two factor authentication setup not started")
+	}
+
+	step, ok := auth.ValidateTOTPCode(user.TotpSecret.String, codeReq.Code, time.Now(), 0)
+	if !ok {
+		return respondWithError(c, http.StatusBadRequest, "invalid two factor code")
+	}
+
+	codes, err := cfg.replaceRecoveryCodes(req.Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create recovery codes: %v", err))
+	}
+
+	err = cfg.DB.EnableTOTP(
+		req.Context(),
+		database.EnableTOTPParams{
+			TotpEnabledAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			TotpLastStep:  step,
+			UpdatedAt:     time.Now(),
+			ID:            user.ID,
+		},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt enable two factor authentication: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+func (cfg *ApiConfig) HandleRegenerateRecoveryCodes(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var codeReq TwoFactorCodeReq
+	if err := json.Unmarshal(requestBytes, &codeReq); err != nil || codeReq.Code == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	user, err := cfg.DB.GetUserByID(req.Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	if !user.TotpEnabledAt.Valid {
+		return respondWithError(c, http.StatusBadRequest, "two factor authentication not enabled")
+	}
+
+	ok, err := cfg.verifySecondFactor(req.Context(), user, codeReq.Code)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt verify two factor code: %v", err))
+	}
+	if !ok {
+		return respondWithError(c, http.StatusBadRequest, "invalid two factor code")
+	}
+
+	codes, err := cfg.replaceRecoveryCodes(req.Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt create recovery codes: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+type DisableTwoFactorReq struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleDisableTwoFactor(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var disableReq DisableTwoFactorReq
+	if err := json.Unmarshal(requestBytes, &disableReq); err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	if disableReq.Password == "" || disableReq.Code == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	user, err := cfg.DB.GetUserByID(req.Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	if !user.TotpEnabledAt.Valid {
+		return respondWithError(c, http.StatusBadRequest, "two factor authentication not enabled")
+	}
+
+	if err := comparePassword(req.Context(), user.HashedPassword, disableReq.Password); err != nil {
+		return respondWithError(c, http.StatusUnauthorized, "invalid password")
+	}
+
+	ok, err := cfg.verifySecondFactor(req.Context(), user, disableReq.Code)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt verify two factor code: %v", err))
+	}
+	if !ok {
+		return respondWithError(c, http.StatusBadRequest, "invalid two factor code")
+	}
+
+	err = cfg.DB.DisableTOTP(req.Context(), database.DisableTOTPParams{UpdatedAt: time.Now(), ID: user.ID})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt disable two factor authentication: %v", err))
+	}
+
+	err = cfg.DB.DeleteRecoveryCodes(req.Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt delete recovery codes: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, TwoFactorRes{Message: "two factor authentication disabled"})
+}
+
+type LoginTwoFactorReq struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	DeviceName     string `json:"device_name,omitempty"`
+}
+
+// HandleLoginTwoFactor is the second step of a login for users with two factor authentication,
+// it exchanges the challenge token from HandleLoginUser and a TOTP or recovery code for a session.
+func (cfg *ApiConfig) HandleLoginTwoFactor(c echo.Context) error {
+	req := c.Request()
+	defer req.Body.Close()
+
+	requestBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "coudln't read req body bytes")
+	}
+
+	var loginReq LoginTwoFactorReq
+	if err := json.Unmarshal(requestBytes, &loginReq); err != nil {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	if loginReq.ChallengeToken == "" || loginReq.Code == "" {
+		return respondWithError(c, http.StatusBadRequest, "request body invalid")
+	}
+
+	userID, err := auth.ValidateChallengeJWTToken(loginReq.ChallengeToken, auth.PurposeTwoFactor, cfg.Config.JWTSecret)
+	if err != nil {
+		return respondWithError(c, http.StatusUnauthorized, "invalid or expired challenge token")
+	}
+
+	user, err := cfg.DB.GetUserByID(req.Context(), userID)
+	if err != nil || !user.TotpEnabledAt.Valid {
+		return respondWithError(c, http.StatusUnauthorized, "invalid or expired challenge token")
+	}
+
+	if user.DisabledAt.Valid {
+		return respondWithError(c, http.StatusForbidden, "account disabled")
+	}
+
+	if allowed, err := cfg.checkLoginAllowed(c, user.ID); !allowed {
+		return err
+	}
+
+	ok, err := cfg.verifySecondFactor(req.Context(), user, loginReq.Code)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt verify two factor code: %v", err))
+	}
+	if !ok {
+		if err := cfg.recordFailedLogin(c, &user); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt record failed login: %v", err))
+		}
+		return respondWithError(c, http.StatusUnauthorized, "invalid two factor code")
+	}
+
+	err = cfg.clearAccountLock(req.Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt reset failed logins: %v", err))
+	}
+
+	loginRes, err := cfg.startSession(c, user.ID, loginReq.DeviceName)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, err.Error())
+	}
+	cfg.Metrics.RecordLogin(metrics.LoginSuccess)
+
+	return c.JSON(http.StatusOK, loginRes)
+}
@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/magicznykacpur/taskin-backend/auth"
 	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/metrics"
 )
 
 type CreateUserReq struct {
@@ -22,8 +25,9 @@ type CreateUserReq struct {
 }
 
 type CreateUserRes struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 func (cfg *ApiConfig) HandleCreateUser(c echo.Context) error {
@@ -44,7 +48,7 @@ func (cfg *ApiConfig) HandleCreateUser(c echo.Context) error {
 		return respondWithError(c, http.StatusBadRequest, "request body invalid")
 	}
 
-	hash, err := auth.HashPassword(userReq.Password)
+	hash, err := hashPassword(c.Request().Context(), userReq.Password)
 	if err != nil {
 		return respondWithError(c, http.StatusInternalServerError, "coudlnt hash password")
 	}
@@ -67,17 +71,38 @@ func (cfg *ApiConfig) HandleCreateUser(c echo.Context) error {
 		return respondWithError(c, http.StatusBadRequest, "user with that email already exists")
 	}
 
+	user, err := cfg.DB.GetUserByEmail(req.Context(), userReq.Email)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("coudlnt create user: %v", err))
+	}
+
+	err = cfg.DB.AssignUserRole(req.Context(), database.AssignUserRoleParams{UserID: user.ID, Role: RoleUser, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt assign role: %v", err))
+	}
+
+	// The account exists at this point, a failed email can be sent again from /api/verify-email/resend.
+	err = cfg.sendVerificationEmail(req.Context(), user, user.Email)
+	if err != nil {
+		log.Printf("couldnt send verification email for %s: %v", userReq.Email, err)
+	}
+
 	return c.JSON(http.StatusCreated, CreateUserRes{Username: userReq.Username, Email: userReq.Email})
 }
 
 type LoginReq struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name,omitempty"`
 }
 
+// LoginRes either holds the tokens of a new session or, for users with two factor
+// authentication, a challenge token to finish the login with at /api/login/2fa.
 type LoginRes struct {
-	JWTToken     string `json:"jwt_token"`
-	RefreshToken string `json:"refresh_token"`
+	JWTToken          string `json:"jwt_token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 func (cfg *ApiConfig) HandleLoginUser(c echo.Context) error {
@@ -98,55 +123,55 @@ func (cfg *ApiConfig) HandleLoginUser(c echo.Context) error {
 		return respondWithError(c, http.StatusBadRequest, "request body invalid")
 	}
 
+	if allowed, err := cfg.checkLoginAllowed(c, ""); !allowed {
+		return err
+	}
+
 	user, err := cfg.DB.GetUserByEmail(c.Request().Context(), loginReq.Email)
 	if err != nil {
+		if err := cfg.recordFailedLogin(c, nil); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt record failed login: %v", err))
+		}
 		return respondWithError(c, http.StatusUnauthorized, "invalid email or password")
 	}
 
-	err = auth.ComparePassword(user.HashedPassword, loginReq.Password)
+	if allowed, err := cfg.checkLoginAllowed(c, user.ID); !allowed {
+		return err
+	}
+
+	err = comparePassword(c.Request().Context(), user.HashedPassword, loginReq.Password)
 	if err != nil {
+		if err := cfg.recordFailedLogin(c, &user); err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt record failed login: %v", err))
+		}
 		return respondWithError(c, http.StatusUnauthorized, "invalid email or password")
 	}
 
-	validRefreshToken, err := cfg.DB.GetValidRefreshTokenForUserId(
-		c.Request().Context(),
-		database.GetValidRefreshTokenForUserIdParams{
-			UserID:    user.ID,
-			ExpiresAt: time.Now(),
-		},
-	)
-
-	if validRefreshToken != (database.RefreshToken{}) {
-		jwtToken, err := auth.GenerateJWTToken(user.ID, os.Getenv("JWT_SECRET"), time.Hour)
-		if err != nil {
-			return respondWithError(c, http.StatusInternalServerError, "couldnt generate jwt token")
-		}
+	if user.DisabledAt.Valid {
+		return respondWithError(c, http.StatusForbidden, "account disabled")
+	}
 
-		return c.JSON(http.StatusOK, LoginRes{JWTToken: jwtToken, RefreshToken: validRefreshToken.Token})
-	} else {
-		refreshToken, err := auth.GenerateRefreshToken()
+	if user.TotpEnabledAt.Valid {
+		challengeToken, err := auth.GenerateChallengeJWTToken(user.ID, auth.PurposeTwoFactor, cfg.Config.JWTSecret, twoFactorChallengeLifetime)
 		if err != nil {
-			return respondWithError(c, http.StatusInternalServerError, "couldnt generate refresh token")
+			return respondWithError(c, http.StatusInternalServerError, "couldnt generate challenge token")
 		}
 
-		err = cfg.DB.CreateRefreshToken(c.Request().Context(), database.CreateRefreshTokenParams{
-			UserID:    user.ID,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Token:     refreshToken,
-			ExpiresAt: time.Now().Add(time.Hour * 24 * 31),
-		})
-		if err != nil {
-			return respondWithError(c, http.StatusInternalServerError, "couldnt create refresh token")
-		}
+		return c.JSON(http.StatusOK, LoginRes{TwoFactorRequired: true, ChallengeToken: challengeToken})
+	}
 
-		jwtToken, err := auth.GenerateJWTToken(user.ID, os.Getenv("JWT_SECRET"), time.Hour)
-		if err != nil {
-			return respondWithError(c, http.StatusInternalServerError, "couldnt generate jwt token")
-		}
+	err = cfg.clearAccountLock(c.Request().Context(), user.ID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt reset failed logins: %v", err))
+	}
 
-		return c.JSON(http.StatusOK, LoginRes{JWTToken: jwtToken, RefreshToken: refreshToken})
+	loginRes, err := cfg.startSession(c, user.ID, loginReq.DeviceName)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, err.Error())
 	}
+	cfg.Metrics.RecordLogin(metrics.LoginSuccess)
+
+	return c.JSON(http.StatusOK, loginRes)
 }
 
 type LogoutRes struct {
@@ -156,24 +181,50 @@ type LogoutRes struct {
 func (cfg *ApiConfig) HandleLogoutUser(c echo.Context) error {
 	userID := c.Request().Header.Get("userID")
 
+	_, err := cfg.revokeSession(c, c.Request().Header.Get("sessionID"), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt revoke session")
+	}
+
+	return c.JSON(http.StatusOK, LogoutRes{Message: "user successfully logged out"})
+}
+
+func (cfg *ApiConfig) HandleLogoutAll(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
 	user, err := cfg.DB.GetUserByID(c.Request().Context(), userID)
 	if err != nil {
 		return respondWithError(c, http.StatusNotFound, "user not found")
 	}
 
-	err = cfg.DB.RevokeRefreshToken(c.Request().Context(), user.ID)
+	err = cfg.revokeUserSessions(c.Request().Context(), user.ID)
 	if err != nil {
-		return respondWithError(c, http.StatusInternalServerError, "couldnt revoke refresh token")
+		return respondWithError(c, http.StatusInternalServerError, "couldnt revoke sessions")
 	}
 
-	return c.JSON(http.StatusOK, LogoutRes{Message: "user successfully logged out"})
+	return c.JSON(http.StatusOK, LogoutRes{Message: "user successfully logged out everywhere"})
 }
 
 type UserRes struct {
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	EmailVerified    bool   `json:"email_verified"`
+	PendingEmail     string `json:"pending_email,omitempty"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+func mapUserToUserRes(user database.User) UserRes {
+	return UserRes{
+		Username:         user.Username,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		PendingEmail:     user.PendingEmail.String,
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+		CreatedAt:        user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        user.UpdatedAt.Format(time.RFC3339),
+	}
 }
 
 func (cfg *ApiConfig) HandleGetMe(c echo.Context) error {
@@ -183,15 +234,7 @@ func (cfg *ApiConfig) HandleGetMe(c echo.Context) error {
 		return respondWithError(c, http.StatusNotFound, "user not found")
 	}
 
-	return c.JSON(
-		200,
-		UserRes{
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
-		},
-	)
+	return c.JSON(200, mapUserToUserRes(user))
 }
 
 type UpdateUserReq struct {
@@ -224,17 +267,24 @@ func (cfg *ApiConfig) HandleUpdateUser(c echo.Context) error {
 		return respondWithError(c, http.StatusNotFound, "user not found")
 	}
 
-	email, username, hashedPassword, err := retrieveValuesFromUserUpdateReq(updateUserReq, user)
+	email, username, hashedPassword, err := retrieveValuesFromUserUpdateReq(c.Request().Context(), updateUserReq, user)
 	if err != nil {
 		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt hash password: %v", err))
 	}
 
+	if email != user.Email {
+		if _, err := cfg.DB.GetUserByEmail(req.Context(), email); err == nil {
+			return respondWithError(c, http.StatusBadRequest, "user with that email already exists")
+		}
+	}
+
+	// A new email only replaces the current one once it's verified, until then it's pending.
 	updatedUser, err := cfg.DB.UpdateUserByID(
 		req.Context(),
 		database.UpdateUserByIDParams{
 			ID:             user.ID,
 			UpdatedAt:      time.Now(),
-			Email:          email,
+			Email:          user.Email,
 			Username:       username,
 			HashedPassword: hashedPassword,
 		},
@@ -243,18 +293,29 @@ func (cfg *ApiConfig) HandleUpdateUser(c echo.Context) error {
 		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt update user: %v", err))
 	}
 
-	return c.JSON(
-		http.StatusOK,
-		UserRes{
-			Email:     updatedUser.Email,
-			Username:  updatedUser.Username,
-			CreatedAt: updatedUser.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: updatedUser.UpdatedAt.Format(time.RFC3339),
-		},
-	)
+	if updateUserReq.Email != "" && email != user.PendingEmail.String {
+		pendingEmail := sql.NullString{String: email, Valid: email != user.Email}
+
+		updatedUser, err = cfg.DB.SetPendingEmail(
+			req.Context(),
+			database.SetPendingEmailParams{PendingEmail: pendingEmail, UpdatedAt: time.Now(), ID: user.ID},
+		)
+		if err != nil {
+			return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt update user: %v", err))
+		}
+
+		if pendingEmail.Valid {
+			err = cfg.sendVerificationEmail(req.Context(), updatedUser, pendingEmail.String)
+			if err != nil {
+				return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt send verification email: %v", err))
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, mapUserToUserRes(updatedUser))
 }
 
-func retrieveValuesFromUserUpdateReq(updateUserReq UpdateUserReq, user database.User) (string, string, string, error) {
+func retrieveValuesFromUserUpdateReq(ctx context.Context, updateUserReq UpdateUserReq, user database.User) (string, string, string, error) {
 	email := updateUserReq.Email
 	if email == "" {
 		email = user.Email
@@ -271,7 +332,7 @@ func retrieveValuesFromUserUpdateReq(updateUserReq UpdateUserReq, user database.
 	if password == "" {
 		hashedPassword = user.HashedPassword
 	} else {
-		hashedPassword, err := auth.HashPassword(password)
+		hashedPassword, err := hashPassword(ctx, password)
 
 		if err != nil {
 			return "", "", "", err
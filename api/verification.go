@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/mail"
+)
+
+const (
+	emailVerificationTokenLifetime = 24 * time.Hour
+
+	// A user can ask for a new verification email once per cooldown and at most
+	// maxVerificationEmailsPerWindow times per window.
+	verificationEmailCooldown      = time.Minute
+	verificationEmailWindow        = time.Hour
+	maxVerificationEmailsPerWindow = 5
+)
+
+// sendVerificationEmail emails a link that verifies email for the user, every link sent before
+// it stops working.
+func (cfg *ApiConfig) sendVerificationEmail(ctx context.Context, user database.User, email string) error {
+	err := cfg.DB.InvalidateEmailVerificationTokens(
+		ctx,
+		database.InvalidateEmailVerificationTokensParams{UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, UserID: user.ID},
+	)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.DB.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: auth.HashToken(token),
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTokenLifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.sendMail(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nuse the following link to verify your email address, it expires in %d hours:\n\n%s\n\nIf you didn't use this address on Taskin, you can ignore this email.",
+			user.Username, int(emailVerificationTokenLifetime.Hours()), cfg.appLink("/verify-email", token),
+		),
+	})
+}
+
+// HandleVerifyEmail marks the address the token was sent to as verified. For a pending email
+// change this is the point where the user's email actually changes.
+func (cfg *ApiConfig) HandleVerifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return respondWithError(c, http.StatusBadRequest, "token needs to be specified as query parameter")
+	}
+
+	verificationToken, err := cfg.DB.GetEmailVerificationTokenByHash(c.Request().Context(), auth.HashToken(token))
+	if err != nil {
+		return respondWithError(c, http.StatusBadRequest, "invalid or expired verification token")
+	}
+
+	used, err := cfg.DB.UseEmailVerificationToken(
+		c.Request().Context(),
+		database.UseEmailVerificationTokenParams{UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, ID: verificationToken.ID},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, "couldnt use verification token")
+	}
+	if used == 0 {
+		return respondWithError(c, http.StatusBadRequest, "invalid or expired verification token")
+	}
+
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), verificationToken.UserID)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	if verificationToken.Email != user.Email && verificationToken.Email != user.PendingEmail.String {
+		return respondWithError(c, http.StatusBadRequest, "invalid or expired verification token")
+	}
+
+	verifiedUser, err := cfg.DB.VerifyUserEmail(
+		c.Request().Context(),
+		database.VerifyUserEmailParams{
+			Email:           verificationToken.Email,
+			EmailVerifiedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt:       time.Now(),
+			ID:              user.ID,
+		},
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return respondWithError(c, http.StatusBadRequest, "user with that email already exists")
+	}
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt verify email: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, mapUserToUserRes(verifiedUser))
+}
+
+type ResendVerificationRes struct {
+	Message string `json:"message"`
+}
+
+func (cfg *ApiConfig) HandleResendVerification(c echo.Context) error {
+	userID := c.Request().Header.Get("userID")
+
+	user, err := cfg.DB.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return respondWithError(c, http.StatusNotFound, "user not found")
+	}
+
+	email := user.Email
+	if user.PendingEmail.Valid {
+		email = user.PendingEmail.String
+	} else if user.EmailVerifiedAt.Valid {
+		return respondWithError(c, http.StatusBadRequest, "email already verified")
+	}
+
+	latest, err := cfg.DB.GetLatestEmailVerificationToken(c.Request().Context(), user.ID)
+	if err == nil {
+		if wait := verificationEmailCooldown - time.Since(latest.CreatedAt); wait > 0 {
+			return respondTooManyVerificationEmails(c, wait)
+		}
+	}
+
+	sent, err := cfg.DB.CountEmailVerificationTokensSince(
+		c.Request().Context(),
+		database.CountEmailVerificationTokensSinceParams{UserID: user.ID, CreatedAt: time.Now().UTC().Add(-verificationEmailWindow)},
+	)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt count verification emails: %v", err))
+	}
+	if sent >= maxVerificationEmailsPerWindow {
+		return respondTooManyVerificationEmails(c, verificationEmailWindow)
+	}
+
+	err = cfg.sendVerificationEmail(c.Request().Context(), user, email)
+	if err != nil {
+		return respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("couldnt send verification email: %v", err))
+	}
+
+	return c.JSON(http.StatusAccepted, ResendVerificationRes{Message: fmt.Sprintf("verification email sent to %s", email)})
+}
+
+func respondTooManyVerificationEmails(c echo.Context, retryAfter time.Duration) error {
+	setRetryAfter(c, retryAfter)
+
+	return respondWithError(c, http.StatusTooManyRequests, "verification email was sent recently, try again later")
+}
@@ -9,47 +9,125 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// PurposeTwoFactor marks the challenge token handed out between the password
+// and the TOTP step of a login.
+const PurposeTwoFactor = "2fa"
+
+// Claims are the claims of every jwt we issue, SessionID ties the token to the
+// session it was issued for so that revoking the session invalidates the token.
+// Purpose is only set on challenge tokens, which can't be used as access tokens.
+type Claims struct {
+	SessionID string `json:"sid,omitempty"`
+	Purpose   string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
 func GenerateJWTToken(userID string, secret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "taskin-backend",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID,
+	return GenerateSessionJWTToken(userID, "", secret, expiresIn)
+}
+
+func GenerateSessionJWTToken(userID, sessionID, secret string, expiresIn time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "taskin-backend",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID,
+		},
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// GenerateChallengeJWTToken returns a short lived token that only proves one step of a
+// flow was completed, like the password step of a two factor login.
+func GenerateChallengeJWTToken(userID, purpose, secret string, expiresIn time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "taskin-backend",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID,
+		},
 	})
 	return token.SignedString([]byte(secret))
 }
 
+func ValidateChallengeJWTToken(tokenString, purpose, secret string) (string, error) {
+	claims, err := parseJWTToken(tokenString, secret)
+	if err != nil {
+		return "", err
+	}
+
+	if claims.Purpose != purpose {
+		return "", fmt.Errorf("token is not a %s challenge", purpose)
+	}
+
+	return claims.Subject, nil
+}
+
 func ValidateJWTToken(tokenString, secret string) (string, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims,
+	claims, err := ValidateSessionJWTToken(tokenString, secret)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+func ValidateSessionJWTToken(tokenString, secret string) (Claims, error) {
+	claims, err := parseJWTToken(tokenString, secret)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if claims.Purpose != "" {
+		return Claims{}, fmt.Errorf("challenge token can't be used for authentication")
+	}
+
+	return claims, nil
+}
+
+func parseJWTToken(tokenString, secret string) (Claims, error) {
+	claims := Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, &claims,
 		func(t *jwt.Token) (interface{}, error) {
 			return []byte(secret), nil
 		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
 	)
 
 	if err != nil {
-		return "", err
+		return Claims{}, err
 	}
 
-	userID, err := claims.GetSubject()
-	if err != nil {
-		return "", err
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("token has no subject")
 	}
 
-	return userID, nil
+	return claims, nil
+}
+
+func GetBearerToken(header http.Header) (string, error) {
+	return getAuthorization(header, "Bearer")
+}
+
+// GetApiKey returns the key from an "Authorization: ApiKey <key>" header.
+func GetApiKey(header http.Header) (string, error) {
+	return getAuthorization(header, "ApiKey")
 }
 
-func GetAuthTokensFromHeaders(header http.Header) (string, string, error) {
-	refreshToken := header.Get("RefreshToken")
-	bearerToken := header.Get("Authorization")
-	if bearerToken == "" || refreshToken == "" {
-		return "", "", fmt.Errorf("missing authorization")
+func getAuthorization(header http.Header, scheme string) (string, error) {
+	authorization := header.Get("Authorization")
+	if authorization == "" {
+		return "", fmt.Errorf("missing authorization")
 	}
 
-	parts := strings.Split(bearerToken, " ")
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("token malformed")
+	parts := strings.Split(authorization, " ")
+	if len(parts) != 2 || parts[0] != scheme {
+		return "", fmt.Errorf("token malformed")
 	}
 
-	return refreshToken, parts[1], nil
+	return parts[1], nil
 }
@@ -15,3 +15,8 @@ func GenerateRefreshToken() (string, error) {
 
 	return fmt.Sprintf("%x", buf), nil
 }
+
+// HashRefreshToken returns the digest refresh tokens are stored and looked up by.
+func HashRefreshToken(token string) string {
+	return HashToken(token)
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -45,4 +46,51 @@ func TestExpiredJWTToken(t *testing.T) {
 	_, err = auth.ValidateJWTToken(tokenString, secret)
 
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+
+func TestGetBearerToken(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer some-token")
+
+	token, err := auth.GetBearerToken(header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "some-token", token)
+
+	for _, value := range []string{"", "some-token", "Basic some-token", "Bearer some token"} {
+		header.Set("Authorization", value)
+		_, err := auth.GetBearerToken(header)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestChallengeJWTToken(t *testing.T) {
+	token, err := auth.GenerateChallengeJWTToken("user-id", auth.PurposeTwoFactor, "secret", time.Minute)
+	assert.NoError(t, err)
+
+	userID, err := auth.ValidateChallengeJWTToken(token, auth.PurposeTwoFactor, "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "user-id", userID)
+
+	_, err = auth.ValidateSessionJWTToken(token, "secret")
+	assert.Error(t, err)
+
+	sessionToken, err := auth.GenerateSessionJWTToken("user-id", "session-id", "secret", time.Minute)
+	assert.NoError(t, err)
+
+	_, err = auth.ValidateChallengeJWTToken(sessionToken, auth.PurposeTwoFactor, "secret")
+	assert.Error(t, err)
+}
+
+func TestGetApiKey(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "ApiKey tsk_key")
+
+	key, err := auth.GetApiKey(header)
+	assert.NoError(t, err)
+	assert.Equal(t, "tsk_key", key)
+
+	header.Set("Authorization", "Bearer tsk_key")
+	_, err = auth.GetApiKey(header)
+	assert.Error(t, err)
+}
@@ -13,4 +13,15 @@ func TestRefreshToken(t *testing.T) {
 	assert.NoError(t, err)
 	// 64 bytes converted to hexadecimal == 128
 	assert.Equal(t, 128, len(token))
-}
\ No newline at end of file
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := auth.GenerateRefreshToken()
+	assert.NoError(t, err)
+
+	hash := auth.HashRefreshToken(token)
+
+	assert.Equal(t, 64, len(hash))
+	assert.NotEqual(t, token, hash)
+	assert.Equal(t, hash, auth.HashRefreshToken(token))
+}
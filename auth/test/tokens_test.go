@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateToken(t *testing.T) {
+	token, err := auth.GenerateToken()
+	assert.NoError(t, err)
+	// 32 bytes converted to hexadecimal == 64
+	assert.Equal(t, 64, len(token))
+
+	other, err := auth.GenerateToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestHashToken(t *testing.T) {
+	hash := auth.HashToken("token")
+
+	assert.Equal(t, 64, len(hash))
+	assert.Equal(t, hash, auth.HashToken("token"))
+	assert.NotEqual(t, hash, auth.HashToken("other token"))
+}
+
+func TestGenerateApiKey(t *testing.T) {
+	key, prefix, err := auth.GenerateApiKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, auth.ApiKeyPrefix))
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Equal(t, len(auth.ApiKeyPrefix)+64, len(key))
+}
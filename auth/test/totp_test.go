@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// base32 of the ASCII secret "12345678901234567890" used by the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := auth.TOTPCode(rfcSecret, auth.TOTPStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := auth.TOTPStep(now)
+
+	matched, ok := auth.ValidateTOTPCode(rfcSecret, "081804", now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, step, matched)
+
+	// codes from the neighbouring steps are accepted for clock drift
+	previous, _ := auth.TOTPCode(rfcSecret, step-1)
+	_, ok = auth.ValidateTOTPCode(rfcSecret, previous, now, 0)
+	assert.True(t, ok)
+
+	old, _ := auth.TOTPCode(rfcSecret, step-2)
+	_, ok = auth.ValidateTOTPCode(rfcSecret, old, now, 0)
+	assert.False(t, ok)
+
+	// a code can't be used again once its step was used
+	_, ok = auth.ValidateTOTPCode(rfcSecret, "081804", now, step)
+	assert.False(t, ok)
+
+	_, ok = auth.ValidateTOTPCode(rfcSecret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	// 20 bytes in base32 without padding == 32
+	assert.Equal(t, 32, len(secret))
+
+	_, err = auth.TOTPCode(secret, 1)
+	assert.NoError(t, err)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := auth.TOTPURI("Taskin", "email@test.com", rfcSecret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Taskin:email@test.com?"))
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=Taskin")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := auth.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, code, auth.NormalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", " "))))
+	}
+}
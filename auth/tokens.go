@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateToken returns a random 256 bit token for single use links like password resets.
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken returns the digest random tokens are stored and looked up by. The tokens
+// carry enough randomness that a plain SHA-256 is sufficient, unlike passwords.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ApiKeyPrefix starts every api key, so leaked keys are easy to recognize.
+const ApiKeyPrefix = "tsk_"
+
+// GenerateApiKey returns a new api key and the short prefix it's shown by after creation,
+// the key itself is only stored hashed with HashToken.
+func GenerateApiKey() (string, string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	key := ApiKeyPrefix + token
+
+	return key, key[:len(ApiKeyPrefix)+8], nil
+}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+)
+
+const minJWTSecretLength = 32
+
+type Config struct {
+	Port     int
+	DBString string
+	// AutoMigrate applies pending migrations when the server starts.
+	AutoMigrate bool
+	JWTSecret   string
+	// AppURL is the base url of the web app, links in emails point there.
+	AppURL string
+
+	Server       Server
+	Mail         Mail
+	Reminders    Reminders
+	LoginLockout LoginLockout
+	RateLimits   RateLimits
+	Tracing      Tracing
+}
+
+// Server holds the timeouts of the http server. On shutdown the server keeps serving for
+// DrainDelay with readiness failing, so load balancers can take it out of rotation, and then
+// in-flight requests get ShutdownTimeout to finish.
+type Server struct {
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+	// TrustedProxies are the addresses allowed to tell the client ip in X-Forwarded-For,
+	// without any the address of the connection is the client's.
+	TrustedProxies []*net.IPNet
+}
+
+// Mail picks the mailer, SMTP when SMTPAddr is set, files in Dir when that is, and the log otherwise.
+type Mail struct {
+	From         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	Dir          string
+}
+
+type Reminders struct {
+	WebhookURL    string
+	WebhookSecret string
+}
+
+// LoginLockout configures the brute force protection of the login endpoints. Failures older
+// than Window are forgotten.
+type LoginLockout struct {
+	// After BackoffAfter failures every further attempt on the account has to wait BaseDelay,
+	// doubled with each failure over the threshold and capped at MaxDelay.
+	BackoffAfter int64
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// After LockAfter failures the account is locked for LockDuration and its owner is notified.
+	LockAfter    int64
+	LockDuration time.Duration
+	// IPBackoffAfter is the backoff threshold for a client ip, it's higher than the account one
+	// since a lot of users can share an address.
+	IPBackoffAfter int64
+	Window         time.Duration
+}
+
+var DefaultLoginLockout = LoginLockout{
+	BackoffAfter:   5,
+	BaseDelay:      time.Second,
+	MaxDelay:       15 * time.Minute,
+	LockAfter:      10,
+	LockDuration:   30 * time.Minute,
+	IPBackoffAfter: 20,
+	Window:         time.Hour,
+}
+
+// RateLimits are the limits of the route groups, Global applies to every request by ip, Auth to
+// the anonymous auth routes by ip and API and Search to logged in routes by user.
+type RateLimits struct {
+	Global ratelimit.Limit
+	Auth   ratelimit.Limit
+	API    ratelimit.Limit
+	Search ratelimit.Limit
+}
+
+// Exporters spans can be sent to, TracingNone turns tracing off.
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// Tracing picks where spans are exported. OTLPEndpoint is the url of the collector's otlp http
+// receiver, when empty the standard OTEL_EXPORTER_OTLP_* variables apply. SampleRatio is the
+// share of new traces that are recorded, requests that come with a sampled parent always are.
+type Tracing struct {
+	Exporter     string
+	OTLPEndpoint string
+	SampleRatio  float64
+}
+
+// Addr is the address the server listens on.
+func (cfg Config) Addr() string {
+	return fmt.Sprintf(":%d", cfg.Port)
+}
+
+// DSN is DBString with foreign keys turned on, sqlite leaves them off on every new connection
+// and the ON DELETE clauses of the schema would never fire.
+func (cfg Config) DSN() string {
+	separator := "?"
+	if strings.Contains(cfg.DBString, "?") {
+		separator = "&"
+	}
+
+	return cfg.DBString + separator + "_pragma=foreign_keys(1)"
+}
+
+// setting is a single configuration value. It's read from the flag named after env in kebab
+// case, the environment variable env or the .env file, in that order, falling back to def.
+type setting struct {
+	env   string
+	def   string
+	usage string
+	apply func(cfg *Config, value string) error
+}
+
+var settings = []setting{
+	{"PORT", "8080", "port the server listens on", func(cfg *Config, value string) error {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("must be a port number between 1 and 65535, got %q", value)
+		}
+		cfg.Port = port
+		return nil
+	}},
+	{"DB_STRING", "", "path of the sqlite database", func(cfg *Config, value string) error {
+		if value == "" {
+			return errors.New("is required")
+		}
+		cfg.DBString = value
+		return nil
+	}},
+	{"AUTO_MIGRATE", "true", "apply pending migrations on startup", boolSetting(func(cfg *Config) *bool { return &cfg.AutoMigrate })},
+	{"JWT_SECRET", "", "secret jwt tokens are signed with", func(cfg *Config, value string) error {
+		if len(value) < minJWTSecretLength {
+			return fmt.Errorf("must be at least %d characters long", minJWTSecretLength)
+		}
+		cfg.JWTSecret = value
+		return nil
+	}},
+	{"HTTP_READ_TIMEOUT", "15s", "how long reading a request can take", durationSetting(func(cfg *Config) *time.Duration { return &cfg.Server.ReadTimeout })},
+	{"HTTP_WRITE_TIMEOUT", "30s", "how long writing a response can take", durationSetting(func(cfg *Config) *time.Duration { return &cfg.Server.WriteTimeout })},
+	{"HTTP_IDLE_TIMEOUT", "2m", "how long idle keep-alive connections are kept open", durationSetting(func(cfg *Config) *time.Duration { return &cfg.Server.IdleTimeout })},
+	{"SHUTDOWN_DRAIN_DELAY", "0s", "how long readiness fails before the server stops accepting connections", func(cfg *Config, value string) error {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return fmt.Errorf("must be a duration like 5s, got %q", value)
+		}
+		cfg.Server.DrainDelay = delay
+		return nil
+	}},
+	{"SHUTDOWN_TIMEOUT", "30s", "how long in-flight requests get to finish on shutdown", durationSetting(func(cfg *Config) *time.Duration { return &cfg.Server.ShutdownTimeout })},
+	{"TRUSTED_PROXIES", "", "comma separated ips or cidr ranges of the proxies in front of the server", func(cfg *Config, value string) error {
+		proxies, err := parseIPRanges(value)
+		if err != nil {
+			return err
+		}
+		cfg.Server.TrustedProxies = proxies
+		return nil
+	}},
+	{"APP_URL", "", "base url of the web app", stringSetting(func(cfg *Config) *string { return &cfg.AppURL })},
+	{"MAIL_FROM", "", "sender of emails", stringSetting(func(cfg *Config) *string { return &cfg.Mail.From })},
+	{"SMTP_ADDR", "", "host:port of the smtp server", stringSetting(func(cfg *Config) *string { return &cfg.Mail.SMTPAddr })},
+	{"SMTP_USERNAME", "", "smtp username", stringSetting(func(cfg *Config) *string { return &cfg.Mail.SMTPUsername })},
+	{"SMTP_PASSWORD", "", "smtp password", stringSetting(func(cfg *Config) *string { return &cfg.Mail.SMTPPassword })},
+	{"MAIL_DIR", "", "directory emails are written to instead of being sent", stringSetting(func(cfg *Config) *string { return &cfg.Mail.Dir })},
+	{"REMINDER_WEBHOOK_URL", "", "url reminders are posted to", stringSetting(func(cfg *Config) *string { return &cfg.Reminders.WebhookURL })},
+	{"REMINDER_WEBHOOK_SECRET", "", "secret reminder webhooks are signed with", stringSetting(func(cfg *Config) *string { return &cfg.Reminders.WebhookSecret })},
+	{"LOGIN_BACKOFF_AFTER", strconv.FormatInt(DefaultLoginLockout.BackoffAfter, 10), "failed logins before an account backs off", intSetting(func(cfg *Config) *int64 { return &cfg.LoginLockout.BackoffAfter })},
+	{"LOGIN_LOCK_AFTER", strconv.FormatInt(DefaultLoginLockout.LockAfter, 10), "failed logins before an account is locked", intSetting(func(cfg *Config) *int64 { return &cfg.LoginLockout.LockAfter })},
+	{"LOGIN_LOCK_DURATION", DefaultLoginLockout.LockDuration.String(), "how long accounts stay locked", durationSetting(func(cfg *Config) *time.Duration { return &cfg.LoginLockout.LockDuration })},
+	{"LOGIN_IP_BACKOFF_AFTER", strconv.FormatInt(DefaultLoginLockout.IPBackoffAfter, 10), "failed logins before a client ip backs off", intSetting(func(cfg *Config) *int64 { return &cfg.LoginLockout.IPBackoffAfter })},
+	{"RATE_LIMIT_GLOBAL", "600/1m:100", "limit of every client ip", limitSetting(func(cfg *Config) *ratelimit.Limit { return &cfg.RateLimits.Global })},
+	{"RATE_LIMIT_AUTH", "20/1m:10", "limit of the auth routes per client ip", limitSetting(func(cfg *Config) *ratelimit.Limit { return &cfg.RateLimits.Auth })},
+	{"RATE_LIMIT_API", "300/1m:60", "limit of the api per user", limitSetting(func(cfg *Config) *ratelimit.Limit { return &cfg.RateLimits.API })},
+	{"RATE_LIMIT_SEARCH", "30/1m:10", "limit of task search per user", limitSetting(func(cfg *Config) *ratelimit.Limit { return &cfg.RateLimits.Search })},
+	{"TRACING_EXPORTER", TracingNone, "where spans are exported, none, stdout or otlp", func(cfg *Config, value string) error {
+		switch value {
+		case TracingNone, TracingStdout, TracingOTLP:
+			cfg.Tracing.Exporter = value
+			return nil
+		}
+		return fmt.Errorf("must be none, stdout or otlp, got %q", value)
+	}},
+	{"TRACING_OTLP_ENDPOINT", "", "url of the otlp http trace receiver", stringSetting(func(cfg *Config) *string { return &cfg.Tracing.OTLPEndpoint })},
+	{"TRACING_SAMPLE_RATIO", "1", "share of new traces that are recorded", func(cfg *Config, value string) error {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return fmt.Errorf("must be a number between 0 and 1, got %q", value)
+		}
+		cfg.Tracing.SampleRatio = ratio
+		return nil
+	}},
+}
+
+func stringSetting(field func(cfg *Config) *string) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		*field(cfg) = value
+		return nil
+	}
+}
+
+func boolSetting(field func(cfg *Config) *bool) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		*field(cfg) = parsed
+		return nil
+	}
+}
+
+func intSetting(field func(cfg *Config) *int64) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("must be a non negative number, got %q", value)
+		}
+		*field(cfg) = parsed
+		return nil
+	}
+}
+
+func durationSetting(field func(cfg *Config) *time.Duration) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("must be a positive duration like 30m, got %q", value)
+		}
+		*field(cfg) = parsed
+		return nil
+	}
+}
+
+func limitSetting(field func(cfg *Config) *ratelimit.Limit) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = limit
+		return nil
+	}
+}
+
+// parseIPRanges parses a comma separated list of cidr ranges, a bare ip is a range of its own.
+func parseIPRanges(value string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !strings.Contains(field, "/") {
+			ip := net.ParseIP(field)
+			if ip == nil {
+				return nil, fmt.Errorf("must be ips or cidr ranges, got %q", field)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipRange, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, fmt.Errorf("must be ips or cidr ranges, got %q", field)
+		}
+		ranges = append(ranges, ipRange)
+	}
+
+	return ranges, nil
+}
+
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// Load builds the configuration from command line flags, the environment and the .env file,
+// in that order of precedence. The .env file is optional unless -env-file points somewhere
+// else. Every invalid setting is reported in the returned error.
+func Load(args []string) (Config, error) {
+	return LoadFrom(args, os.LookupEnv, os.Stderr)
+}
+
+// LoadFrom is Load with the environment read through lookupEnv, flag errors and -help go to output.
+func LoadFrom(args []string, lookupEnv func(string) (string, bool), output io.Writer) (Config, error) {
+	flags := flag.NewFlagSet("taskin", flag.ContinueOnError)
+	flags.SetOutput(output)
+
+	envFile := flags.String("env-file", ".env", "path of the .env file")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.env] = flags.String(flagName(s.env), s.def, s.usage+" ("+s.env+")")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	setFlags := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	dotEnv, err := readDotEnv(*envFile, setFlags["env-file"])
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{LoginLockout: DefaultLoginLockout}
+	errs := []error{}
+	for _, s := range settings {
+		value, ok := *flagValues[s.env], setFlags[flagName(s.env)]
+		if !ok {
+			value, ok = lookupEnv(s.env)
+		}
+		if !ok {
+			value, ok = dotEnv[s.env]
+		}
+		if !ok {
+			value = s.def
+		}
+
+		if err := s.apply(&cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %w", s.env, err))
+		}
+	}
+
+	return cfg, errors.Join(errs...)
+}
+
+func readDotEnv(path string, required bool) (map[string]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldnt open env file: %w", err)
+	}
+	defer file.Close()
+
+	values, err := ParseDotEnv(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldnt parse %s: %w", path, err)
+	}
+
+	return values, nil
+}
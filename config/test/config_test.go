@@ -0,0 +1,119 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writeEnvFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	assert.NoError(t, err)
+
+	return path
+}
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	path := writeEnvFile(t, "DB_STRING=taskin.db\nJWT_SECRET="+testSecret)
+
+	cfg, err := config.LoadFrom([]string{"-env-file", path}, lookupIn(nil), io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, ":8080", cfg.Addr())
+	assert.Equal(t, "taskin.db", cfg.DBString)
+	assert.Equal(t, "taskin.db?_pragma=foreign_keys(1)", cfg.DSN())
+	assert.Equal(t, "file:taskin.db?mode=ro&_pragma=foreign_keys(1)", config.Config{DBString: "file:taskin.db?mode=ro"}.DSN())
+	assert.Equal(t, testSecret, cfg.JWTSecret)
+	assert.Equal(t, config.DefaultLoginLockout, cfg.LoginLockout)
+	assert.Equal(t, config.Server{
+		ReadTimeout:     15 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		IdleTimeout:     2 * time.Minute,
+		ShutdownTimeout: 30 * time.Second,
+	}, cfg.Server)
+	assert.Equal(t, ratelimit.Limit{Requests: 30, Per: time.Minute, Burst: 10}, cfg.RateLimits.Search)
+	assert.Equal(t, config.Tracing{Exporter: config.TracingNone, SampleRatio: 1}, cfg.Tracing)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeEnvFile(t, "DB_STRING=taskin.db\nJWT_SECRET="+testSecret+"\nPORT=1000\nAPP_URL=https://file.test\nMAIL_FROM=file@taskin.test")
+	env := map[string]string{"PORT": "2000", "APP_URL": "https://env.test"}
+
+	cfg, err := config.LoadFrom([]string{"-env-file", path, "-port", "3000"}, lookupIn(env), io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 3000, cfg.Port)
+	assert.Equal(t, "https://env.test", cfg.AppURL)
+	assert.Equal(t, "file@taskin.test", cfg.Mail.From)
+}
+
+func TestLoadValidates(t *testing.T) {
+	env := map[string]string{
+		"JWT_SECRET":           "short",
+		"PORT":                 "70000",
+		"LOGIN_LOCK_DURATION":  "forever",
+		"SHUTDOWN_TIMEOUT":     "0s",
+		"RATE_LIMIT_API":       "lots",
+		"TRACING_EXPORTER":     "jaeger",
+		"TRACING_SAMPLE_RATIO": "2",
+		"TRUSTED_PROXIES":      "10.0.0.0/8,proxy",
+	}
+
+	_, err := config.LoadFrom([]string{"-env-file", writeEnvFile(t, "")}, lookupIn(env), io.Discard)
+	assert.ErrorContains(t, err, "PORT must be a port number between 1 and 65535")
+	assert.ErrorContains(t, err, "DB_STRING is required")
+	assert.ErrorContains(t, err, "JWT_SECRET must be at least 32 characters long")
+	assert.ErrorContains(t, err, "LOGIN_LOCK_DURATION must be a positive duration")
+	assert.ErrorContains(t, err, "SHUTDOWN_TIMEOUT must be a positive duration")
+	assert.ErrorContains(t, err, "RATE_LIMIT_API limit \"lots\"")
+	assert.ErrorContains(t, err, "TRACING_EXPORTER must be none, stdout or otlp")
+	assert.ErrorContains(t, err, "TRACING_SAMPLE_RATIO must be a number between 0 and 1")
+	assert.ErrorContains(t, err, "TRUSTED_PROXIES must be ips or cidr ranges, got \"proxy\"")
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	env := map[string]string{"DB_STRING": "taskin.db", "JWT_SECRET": testSecret, "TRUSTED_PROXIES": "10.0.0.0/8, 192.0.2.1,::1"}
+
+	cfg, err := config.LoadFrom([]string{"-env-file", writeEnvFile(t, "")}, lookupIn(env), io.Discard)
+	assert.NoError(t, err)
+
+	ranges := []string{}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		ranges = append(ranges, proxy.String())
+	}
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1/32", "::1/128"}, ranges)
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	env := map[string]string{"DB_STRING": "taskin.db", "JWT_SECRET": testSecret}
+
+	// the default .env is optional
+	t.Chdir(t.TempDir())
+	_, err := config.LoadFrom(nil, lookupIn(env), io.Discard)
+	assert.NoError(t, err)
+
+	// one that was asked for isn't
+	_, err = config.LoadFrom([]string{"-env-file", "missing.env"}, lookupIn(env), io.Discard)
+	assert.ErrorContains(t, err, "couldnt open env file")
+
+	_, err = config.LoadFrom([]string{"-env-file", writeEnvFile(t, "broken")}, lookupIn(env), io.Discard)
+	assert.ErrorContains(t, err, "line 1")
+
+	_, err = config.LoadFrom([]string{"-unknown"}, lookupIn(env), io.Discard)
+	assert.Error(t, err)
+}
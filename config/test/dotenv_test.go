@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	values, err := config.ParseDotEnv(strings.NewReader(`
+# database
+DB_STRING=file:taskin.db?_pragma=busy_timeout(5000)
+
+export PORT = 8080 # inline comment
+APP_URL=https://taskin.app/#home
+EMPTY=
+JWT_SECRET="a secret with \"quotes\", a # and\na newline" # comment
+RAW='single $quoted \n value'
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DB_STRING":  "file:taskin.db?_pragma=busy_timeout(5000)",
+		"PORT":       "8080",
+		"APP_URL":    "https://taskin.app/#home",
+		"EMPTY":      "",
+		"JWT_SECRET": "a secret with \"quotes\", a # and\na newline",
+		"RAW":        `single $quoted \n value`,
+	}, values)
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	for _, invalid := range []string{
+		"NO_EQUALS_SIGN",
+		"=value",
+		"TWO WORDS=value",
+		`UNTERMINATED="value`,
+		`UNTERMINATED='value`,
+		`TRAILING="value" junk`,
+	} {
+		_, err := config.ParseDotEnv(strings.NewReader("PORT=8080\n" + invalid))
+		assert.ErrorContains(t, err, "line 2", invalid)
+	}
+}
@@ -0,0 +1,152 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: api_keys.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createApiKey = `-- name: CreateApiKey :one
+INSERT INTO api_keys(id, user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at
+`
+
+type CreateApiKeyParams struct {
+	ID        string
+	UserID    string
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Scopes    string
+	CreatedAt time.Time
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, createApiKey,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		arg.Scopes,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getApiKeyByHash = `-- name: GetApiKeyByHash :one
+SELECT id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = ?
+`
+
+func (q *Queries) GetApiKeyByHash(ctx context.Context, keyHash string) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, getApiKeyByHash, keyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getUserApiKeys = `-- name: GetUserApiKeys :many
+SELECT id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at FROM api_keys
+WHERE user_id = ? AND revoked_at IS NULL
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserApiKeys(ctx context.Context, userID string) ([]ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, getUserApiKeys, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiKey
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Prefix,
+			&i.KeyHash,
+			&i.Scopes,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeApiKey = `-- name: RevokeApiKey :execrows
+UPDATE api_keys
+SET revoked_at = ?
+WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+`
+
+type RevokeApiKeyParams struct {
+	RevokedAt sql.NullTime
+	ID        string
+	UserID    string
+}
+
+func (q *Queries) RevokeApiKey(ctx context.Context, arg RevokeApiKeyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeApiKey, arg.RevokedAt, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchApiKey = `-- name: TouchApiKey :exec
+UPDATE api_keys SET last_used_at = ? WHERE id = ?
+`
+
+type TouchApiKeyParams struct {
+	LastUsedAt sql.NullTime
+	ID         string
+}
+
+func (q *Queries) TouchApiKey(ctx context.Context, arg TouchApiKeyParams) error {
+	_, err := q.db.ExecContext(ctx, touchApiKey, arg.LastUsedAt, arg.ID)
+	return err
+}
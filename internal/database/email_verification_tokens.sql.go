@@ -0,0 +1,131 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: email_verification_tokens.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const countEmailVerificationTokensSince = `-- name: CountEmailVerificationTokensSince :one
+SELECT COUNT(*) FROM email_verification_tokens
+WHERE user_id = ? AND created_at > ?
+`
+
+type CountEmailVerificationTokensSinceParams struct {
+	UserID    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountEmailVerificationTokensSince(ctx context.Context, arg CountEmailVerificationTokensSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEmailVerificationTokensSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createEmailVerificationToken = `-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens(id, user_id, email, token_hash, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type CreateEmailVerificationTokenParams struct {
+	ID        string
+	UserID    string
+	Email     string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createEmailVerificationToken,
+		arg.ID,
+		arg.UserID,
+		arg.Email,
+		arg.TokenHash,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const getEmailVerificationTokenByHash = `-- name: GetEmailVerificationTokenByHash :one
+SELECT id, user_id, email, token_hash, created_at, expires_at, used_at FROM email_verification_tokens WHERE token_hash = ?
+`
+
+func (q *Queries) GetEmailVerificationTokenByHash(ctx context.Context, tokenHash string) (EmailVerificationToken, error) {
+	row := q.db.QueryRowContext(ctx, getEmailVerificationTokenByHash, tokenHash)
+	var i EmailVerificationToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
+const getLatestEmailVerificationToken = `-- name: GetLatestEmailVerificationToken :one
+SELECT id, user_id, email, token_hash, created_at, expires_at, used_at FROM email_verification_tokens
+WHERE user_id = ?
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestEmailVerificationToken(ctx context.Context, userID string) (EmailVerificationToken, error) {
+	row := q.db.QueryRowContext(ctx, getLatestEmailVerificationToken, userID)
+	var i EmailVerificationToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
+const invalidateEmailVerificationTokens = `-- name: InvalidateEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = ?
+WHERE user_id = ? AND used_at IS NULL
+`
+
+type InvalidateEmailVerificationTokensParams struct {
+	UsedAt sql.NullTime
+	UserID string
+}
+
+func (q *Queries) InvalidateEmailVerificationTokens(ctx context.Context, arg InvalidateEmailVerificationTokensParams) error {
+	_, err := q.db.ExecContext(ctx, invalidateEmailVerificationTokens, arg.UsedAt, arg.UserID)
+	return err
+}
+
+const useEmailVerificationToken = `-- name: UseEmailVerificationToken :execrows
+UPDATE email_verification_tokens
+SET used_at = ?1
+WHERE id = ?2 AND used_at IS NULL AND expires_at > ?1
+`
+
+type UseEmailVerificationTokenParams struct {
+	UsedAt sql.NullTime
+	ID     string
+}
+
+func (q *Queries) UseEmailVerificationToken(ctx context.Context, arg UseEmailVerificationTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useEmailVerificationToken, arg.UsedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const taskColumns = `tasks.seq, tasks.id, tasks.created_at, tasks.updated_at, tasks.due_until, tasks.title, tasks.description, tasks.priority, tasks.user_id, tasks.status, tasks.completed_at, tasks.parent_id, tasks.series_id`
+
+var TaskSortColumns = map[string]string{
+	"due_until":  "tasks.due_until",
+	"priority":   "tasks.priority",
+	"created_at": "tasks.created_at",
+	"updated_at": "tasks.updated_at",
+}
+
+type ListTasksParams struct {
+	UserID string
+	SortBy string
+	Desc   bool
+	// AfterValue and AfterID hold the sort key of the last task on the
+	// previous page, AfterID being empty on the first page.
+	AfterValue interface{}
+	AfterID    string
+	Limit      int64
+
+	Tags          []string
+	MatchAllTags  bool
+	Statuses      []string
+	PriorityMin   sql.NullInt64
+	PriorityMax   sql.NullInt64
+	DueBefore     sql.NullTime
+	DueAfter      sql.NullTime
+	OverdueAt     sql.NullTime
+	CreatedBefore sql.NullTime
+	CreatedAfter  sql.NullTime
+	UpdatedBefore sql.NullTime
+	UpdatedAfter  sql.NullTime
+}
+
+// Task timestamps are written in UTC and compared as text,
+// so every time bound has to be converted before it is bound.
+func utcArg(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC()
+	}
+	return value
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func listTasksFilters(arg ListTasksParams) ([]string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if len(arg.Tags) > 0 {
+		tagged := fmt.Sprintf(
+			"tasks.id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name IN (%s)",
+			placeholders(len(arg.Tags)),
+		)
+		for _, tag := range arg.Tags {
+			args = append(args, tag)
+		}
+		if arg.MatchAllTags {
+			tagged += " GROUP BY task_tags.task_id HAVING COUNT(DISTINCT tags.name) = ?"
+			args = append(args, len(arg.Tags))
+		}
+		conditions = append(conditions, tagged+")")
+	}
+	if len(arg.Statuses) > 0 {
+		conditions = append(conditions, fmt.Sprintf("tasks.status IN (%s)", placeholders(len(arg.Statuses))))
+		for _, status := range arg.Statuses {
+			args = append(args, status)
+		}
+	}
+	if arg.PriorityMin.Valid {
+		conditions = append(conditions, "tasks.priority >= ?")
+		args = append(args, arg.PriorityMin.Int64)
+	}
+	if arg.PriorityMax.Valid {
+		conditions = append(conditions, "tasks.priority <= ?")
+		args = append(args, arg.PriorityMax.Int64)
+	}
+	if arg.OverdueAt.Valid {
+		conditions = append(conditions, "tasks.due_until < ? AND tasks.status NOT IN ('done', 'cancelled')")
+		args = append(args, utcArg(arg.OverdueAt.Time))
+	}
+
+	timeRanges := []struct {
+		condition string
+		bound     sql.NullTime
+	}{
+		{"tasks.due_until < ?", arg.DueBefore},
+		{"tasks.due_until > ?", arg.DueAfter},
+		{"tasks.created_at < ?", arg.CreatedBefore},
+		{"tasks.created_at > ?", arg.CreatedAfter},
+		{"tasks.updated_at < ?", arg.UpdatedBefore},
+		{"tasks.updated_at > ?", arg.UpdatedAfter},
+	}
+	for _, timeRange := range timeRanges {
+		if timeRange.bound.Valid {
+			conditions = append(conditions, timeRange.condition)
+			args = append(args, utcArg(timeRange.bound.Time))
+		}
+	}
+
+	return conditions, args
+}
+
+// ListTasks builds its ORDER BY and keyset conditions at runtime,
+// which sqlc cannot express, so it is maintained by hand.
+func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, error) {
+	sortColumn, ok := TaskSortColumns[arg.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort column: %s", arg.SortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+	if arg.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions := []string{
+		"(tasks.user_id = ? OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = ?))",
+	}
+	args := []interface{}{arg.UserID, arg.UserID}
+
+	filterConditions, filterArgs := listTasksFilters(arg)
+	conditions = append(conditions, filterConditions...)
+	args = append(args, filterArgs...)
+
+	if arg.AfterID != "" {
+		afterValue := utcArg(arg.AfterValue)
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND tasks.id %[2]s ?))", sortColumn, comparison))
+		args = append(args, afterValue, afterValue, arg.AfterID)
+	}
+
+	query := fmt.Sprintf(
+		"-- name: ListTasks :many\nSELECT %s FROM tasks\nWHERE %s\nORDER BY %s %s, tasks.id %s\nLIMIT ?",
+		taskColumns,
+		strings.Join(conditions, "\nAND "),
+		sortColumn,
+		direction,
+		direction,
+	)
+	args = append(args, arg.Limit)
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.Seq,
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DueUntil,
+			&i.Title,
+			&i.Description,
+			&i.Priority,
+			&i.UserID,
+			&i.Status,
+			&i.CompletedAt,
+			&i.ParentID,
+			&i.SeriesID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
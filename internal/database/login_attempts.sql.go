@@ -0,0 +1,101 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: login_attempts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const blockLoginAttempts = `-- name: BlockLoginAttempts :exec
+UPDATE login_attempts
+SET blocked_until = ?, locked_at = ?
+WHERE scope = ? AND subject = ?
+`
+
+type BlockLoginAttemptsParams struct {
+	BlockedUntil sql.NullTime
+	LockedAt     sql.NullTime
+	Scope        string
+	Subject      string
+}
+
+func (q *Queries) BlockLoginAttempts(ctx context.Context, arg BlockLoginAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, blockLoginAttempts,
+		arg.BlockedUntil,
+		arg.LockedAt,
+		arg.Scope,
+		arg.Subject,
+	)
+	return err
+}
+
+const clearLoginAttempts = `-- name: ClearLoginAttempts :exec
+DELETE FROM login_attempts WHERE scope = ? AND subject = ?
+`
+
+type ClearLoginAttemptsParams struct {
+	Scope   string
+	Subject string
+}
+
+func (q *Queries) ClearLoginAttempts(ctx context.Context, arg ClearLoginAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, clearLoginAttempts, arg.Scope, arg.Subject)
+	return err
+}
+
+const getLoginAttempt = `-- name: GetLoginAttempt :one
+SELECT scope, subject, failures, last_failed_at, blocked_until, locked_at FROM login_attempts WHERE scope = ? AND subject = ?
+`
+
+type GetLoginAttemptParams struct {
+	Scope   string
+	Subject string
+}
+
+func (q *Queries) GetLoginAttempt(ctx context.Context, arg GetLoginAttemptParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, getLoginAttempt, arg.Scope, arg.Subject)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Scope,
+		&i.Subject,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.BlockedUntil,
+		&i.LockedAt,
+	)
+	return i, err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_attempts(scope, subject, failures, last_failed_at)
+VALUES (?, ?, 1, ?)
+ON CONFLICT(scope, subject) DO UPDATE SET
+    failures = login_attempts.failures + 1,
+    last_failed_at = excluded.last_failed_at
+RETURNING scope, subject, failures, last_failed_at, blocked_until, locked_at
+`
+
+type RecordLoginFailureParams struct {
+	Scope        string
+	Subject      string
+	LastFailedAt time.Time
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, arg.Scope, arg.Subject, arg.LastFailedAt)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Scope,
+		&i.Subject,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.BlockedUntil,
+		&i.LockedAt,
+	)
+	return i, err
+}
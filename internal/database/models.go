@@ -5,19 +5,109 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 )
 
-type RefreshToken struct {
+type ApiKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type EmailVerificationToken struct {
+	ID        string
 	UserID    string
-	Token     string
+	Email     string
+	TokenHash string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	IsRevoked int64
 	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type LoginAttempt struct {
+	Scope        string
+	Subject      string
+	Failures     int64
+	LastFailedAt time.Time
+	BlockedUntil sql.NullTime
+	LockedAt     sql.NullTime
+}
+
+type PasswordResetToken struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Permission struct {
+	Name        string
+	Description string
+}
+
+type RecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	ID         string
+	UserID     string
+	FamilyID   string
+	TokenHash  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ExpiresAt  time.Time
+	ConsumedAt sql.NullTime
+	IsRevoked  int64
+}
+
+type Role struct {
+	Name        string
+	Description string
+}
+
+type RolePermission struct {
+	Role       string
+	Permission string
+}
+
+type Session struct {
+	ID         string
+	UserID     string
+	DeviceName string
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+}
+
+type Tag struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Color     string
+	UserID    string
 }
 
 type Task struct {
+	Seq         int64
 	ID          string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -25,16 +115,74 @@ type Task struct {
 	Title       string
 	Description string
 	Priority    int64
-	Category    string
+	UserID      string
+	Status      string
+	CompletedAt sql.NullTime
+	ParentID    sql.NullString
+	SeriesID    sql.NullString
+}
+
+type TaskReminder struct {
+	ID            string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	TaskID        string
+	UserID        string
+	MinutesBefore int64
+	Channel       string
+	RemindAt      time.Time
+	Status        string
+	Attempts      int64
+	LastError     string
+	ClaimedAt     sql.NullTime
+	SentAt        sql.NullTime
+}
+
+type TaskSeries struct {
+	ID          string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Rule        string
+	StartsAt    time.Time
+	Title       string
+	Description string
+	Priority    int64
 	UserID      string
 }
 
+type TaskShare struct {
+	TaskID    string
+	UserID    string
+	CreatedAt time.Time
+}
+
+type TaskTag struct {
+	TaskID string
+	TagID  string
+}
+
+type TasksFt struct {
+	Title       string
+	Description string
+}
+
 type User struct {
-	ID             string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	Username       string
-	HashedPassword string
-	IsAdmin        int64
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	Username        string
+	HashedPassword  string
+	EmailVerifiedAt sql.NullTime
+	PendingEmail    sql.NullString
+	TotpSecret      sql.NullString
+	TotpEnabledAt   sql.NullTime
+	TotpLastStep    int64
+	DisabledAt      sql.NullTime
+}
+
+type UserRole struct {
+	UserID    string
+	Role      string
+	CreatedAt time.Time
 }
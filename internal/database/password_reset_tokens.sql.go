@@ -0,0 +1,89 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: password_reset_tokens.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens(id, user_id, token_hash, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreatePasswordResetTokenParams struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createPasswordResetToken,
+		arg.ID,
+		arg.UserID,
+		arg.TokenHash,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const getPasswordResetTokenByHash = `-- name: GetPasswordResetTokenByHash :one
+SELECT id, user_id, token_hash, created_at, expires_at, used_at FROM password_reset_tokens WHERE token_hash = ?
+`
+
+func (q *Queries) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, getPasswordResetTokenByHash, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
+const invalidatePasswordResetTokens = `-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = ?
+WHERE user_id = ? AND used_at IS NULL
+`
+
+type InvalidatePasswordResetTokensParams struct {
+	UsedAt sql.NullTime
+	UserID string
+}
+
+func (q *Queries) InvalidatePasswordResetTokens(ctx context.Context, arg InvalidatePasswordResetTokensParams) error {
+	_, err := q.db.ExecContext(ctx, invalidatePasswordResetTokens, arg.UsedAt, arg.UserID)
+	return err
+}
+
+const usePasswordResetToken = `-- name: UsePasswordResetToken :execrows
+UPDATE password_reset_tokens
+SET used_at = ?1
+WHERE id = ?2 AND used_at IS NULL AND expires_at > ?1
+`
+
+type UsePasswordResetTokenParams struct {
+	UsedAt sql.NullTime
+	ID     string
+}
+
+func (q *Queries) UsePasswordResetToken(ctx context.Context, arg UsePasswordResetTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, usePasswordResetToken, arg.UsedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,17 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
+const consumeRefreshToken = `-- name: ConsumeRefreshToken :execrows
+UPDATE refresh_tokens
+SET consumed_at = ?, updated_at = ?
+WHERE id = ? AND consumed_at IS NULL AND is_revoked = 0
+`
+
+type ConsumeRefreshTokenParams struct {
+	ConsumedAt sql.NullTime
+	UpdatedAt  time.Time
+	ID         string
+}
+
+func (q *Queries) ConsumeRefreshToken(ctx context.Context, arg ConsumeRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, consumeRefreshToken, arg.ConsumedAt, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :exec
-INSERT INTO refresh_tokens(user_id, token, created_at, updated_at, is_revoked, expires_at)
-VALUES (?, ?, ?, ?, 0, ?)
+INSERT INTO refresh_tokens(id, user_id, family_id, token_hash, created_at, updated_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
 type CreateRefreshTokenParams struct {
+	ID        string
 	UserID    string
-	Token     string
+	FamilyID  string
+	TokenHash string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	ExpiresAt time.Time
@@ -25,8 +48,10 @@ type CreateRefreshTokenParams struct {
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
 	_, err := q.db.ExecContext(ctx, createRefreshToken,
+		arg.ID,
 		arg.UserID,
-		arg.Token,
+		arg.FamilyID,
+		arg.TokenHash,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.ExpiresAt,
@@ -34,31 +59,29 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 	return err
 }
 
-const getValidRefreshTokenByValue = `-- name: GetValidRefreshTokenByValue :one
-SELECT user_id, token, created_at, updated_at, is_revoked, expires_at FROM refresh_tokens WHERE is_revoked = 0 AND token = ? AND expires_at > ?
+const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
+SELECT id, user_id, family_id, token_hash, created_at, updated_at, expires_at, consumed_at, is_revoked FROM refresh_tokens WHERE token_hash = ?
 `
 
-type GetValidRefreshTokenByValueParams struct {
-	Token     string
-	ExpiresAt time.Time
-}
-
-func (q *Queries) GetValidRefreshTokenByValue(ctx context.Context, arg GetValidRefreshTokenByValueParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getValidRefreshTokenByValue, arg.Token, arg.ExpiresAt)
+func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenByHash, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
+		&i.ID,
 		&i.UserID,
-		&i.Token,
+		&i.FamilyID,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.IsRevoked,
 		&i.ExpiresAt,
+		&i.ConsumedAt,
+		&i.IsRevoked,
 	)
 	return i, err
 }
 
 const getValidRefreshTokenForUserId = `-- name: GetValidRefreshTokenForUserId :one
-SELECT user_id, token, created_at, updated_at, is_revoked, expires_at FROM refresh_tokens WHERE is_revoked = 0 AND expires_at > ? AND user_id = ?
+SELECT id, user_id, family_id, token_hash, created_at, updated_at, expires_at, consumed_at, is_revoked FROM refresh_tokens WHERE is_revoked = 0 AND consumed_at IS NULL AND expires_at > ? AND user_id = ?
 `
 
 type GetValidRefreshTokenForUserIdParams struct {
@@ -70,12 +93,15 @@ func (q *Queries) GetValidRefreshTokenForUserId(ctx context.Context, arg GetVali
 	row := q.db.QueryRowContext(ctx, getValidRefreshTokenForUserId, arg.ExpiresAt, arg.UserID)
 	var i RefreshToken
 	err := row.Scan(
+		&i.ID,
 		&i.UserID,
-		&i.Token,
+		&i.FamilyID,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.IsRevoked,
 		&i.ExpiresAt,
+		&i.ConsumedAt,
+		&i.IsRevoked,
 	)
 	return i, err
 }
@@ -88,3 +114,17 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, userID string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, userID)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET is_revoked = 1, updated_at = ? WHERE family_id = ?
+`
+
+type RevokeRefreshTokenFamilyParams struct {
+	UpdatedAt time.Time
+	FamilyID  string
+}
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, arg.UpdatedAt, arg.FamilyID)
+	return err
+}
@@ -0,0 +1,203 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: roles.sql
+
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const assignUserRole = `-- name: AssignUserRole :exec
+INSERT INTO user_roles(user_id, role, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, role) DO NOTHING
+`
+
+type AssignUserRoleParams struct {
+	UserID    string
+	Role      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) AssignUserRole(ctx context.Context, arg AssignUserRoleParams) error {
+	_, err := q.db.ExecContext(ctx, assignUserRole, arg.UserID, arg.Role, arg.CreatedAt)
+	return err
+}
+
+const countUserPermission = `-- name: CountUserPermission :one
+SELECT COUNT(*) FROM user_roles
+JOIN role_permissions ON role_permissions.role = user_roles.role
+WHERE user_roles.user_id = ? AND role_permissions.permission = ?
+`
+
+type CountUserPermissionParams struct {
+	UserID     string
+	Permission string
+}
+
+func (q *Queries) CountUserPermission(ctx context.Context, arg CountUserPermissionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserPermission, arg.UserID, arg.Permission)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getRoleByName = `-- name: GetRoleByName :one
+SELECT name, description FROM roles WHERE name = ?
+`
+
+func (q *Queries) GetRoleByName(ctx context.Context, name string) (Role, error) {
+	row := q.db.QueryRowContext(ctx, getRoleByName, name)
+	var i Role
+	err := row.Scan(&i.Name, &i.Description)
+	return i, err
+}
+
+const getRolePermissions = `-- name: GetRolePermissions :many
+SELECT role, permission FROM role_permissions ORDER BY role, permission
+`
+
+func (q *Queries) GetRolePermissions(ctx context.Context) ([]RolePermission, error) {
+	rows, err := q.db.QueryContext(ctx, getRolePermissions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RolePermission
+	for rows.Next() {
+		var i RolePermission
+		if err := rows.Scan(&i.Role, &i.Permission); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoles = `-- name: GetRoles :many
+SELECT name, description FROM roles ORDER BY name
+`
+
+func (q *Queries) GetRoles(ctx context.Context) ([]Role, error) {
+	rows, err := q.db.QueryContext(ctx, getRoles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Role
+	for rows.Next() {
+		var i Role
+		if err := rows.Scan(&i.Name, &i.Description); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRolesForUsers = `-- name: GetRolesForUsers :many
+SELECT user_id, role, created_at FROM user_roles WHERE user_id IN (/*SLICE:user_ids*/?) ORDER BY user_id, role
+`
+
+func (q *Queries) GetRolesForUsers(ctx context.Context, userIds []string) ([]UserRole, error) {
+	query := getRolesForUsers
+	var queryParams []interface{}
+	if len(userIds) > 0 {
+		for _, v := range userIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:user_ids*/?", strings.Repeat(",?", len(userIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:user_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserRole
+	for rows.Next() {
+		var i UserRole
+		if err := rows.Scan(&i.UserID, &i.Role, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserRoles = `-- name: GetUserRoles :many
+SELECT user_id, role, created_at FROM user_roles WHERE user_id = ? ORDER BY role
+`
+
+func (q *Queries) GetUserRoles(ctx context.Context, userID string) ([]UserRole, error) {
+	rows, err := q.db.QueryContext(ctx, getUserRoles, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserRole
+	for rows.Next() {
+		var i UserRole
+		if err := rows.Scan(&i.UserID, &i.Role, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeUserRole = `-- name: RemoveUserRole :execrows
+DELETE FROM user_roles
+WHERE user_roles.user_id = ?1 AND user_roles.role = ?2
+AND (
+    user_roles.role != 'admin'
+    OR (
+        SELECT COUNT(*) FROM user_roles AS admin_roles
+        JOIN users AS admins ON admins.id = admin_roles.user_id
+        WHERE admin_roles.role = 'admin' AND admins.disabled_at IS NULL AND admins.id != ?1
+    ) > 0
+)
+`
+
+type RemoveUserRoleParams struct {
+	UserID string
+	Role   string
+}
+
+// The last active admin can't lose the admin role, no row is deleted then.
+func (q *Queries) RemoveUserRole(ctx context.Context, arg RemoveUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeUserRole, arg.UserID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
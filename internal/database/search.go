@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+)
+
+// sqlc cannot resolve the FTS5 hidden column used by MATCH,
+// so this query is maintained by hand in the shape sqlc would generate.
+const searchTasks = `-- name: SearchTasks :many
+SELECT
+    ` + taskColumns + `,
+    highlight(tasks_fts, 0, '<mark>', '</mark>') AS title_highlight,
+    snippet(tasks_fts, 1, '<mark>', '</mark>', '...', 16) AS description_snippet,
+    -bm25(tasks_fts, 5.0, 1.0) AS score
+FROM tasks_fts
+JOIN tasks ON tasks.seq = tasks_fts.rowid
+WHERE tasks_fts MATCH ?1
+AND (tasks.user_id = ?2 OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = ?2))
+ORDER BY score DESC, tasks.id
+LIMIT ?3 OFFSET ?4
+`
+
+type SearchTasksParams struct {
+	Query  string
+	UserID string
+	Limit  int64
+	Offset int64
+}
+
+type SearchTasksRow struct {
+	Task               Task
+	TitleHighlight     string
+	DescriptionSnippet string
+	Score              float64
+}
+
+func (q *Queries) SearchTasks(ctx context.Context, arg SearchTasksParams) ([]SearchTasksRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchTasks, arg.Query, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchTasksRow
+	for rows.Next() {
+		var i SearchTasksRow
+		if err := rows.Scan(
+			&i.Task.Seq,
+			&i.Task.ID,
+			&i.Task.CreatedAt,
+			&i.Task.UpdatedAt,
+			&i.Task.DueUntil,
+			&i.Task.Title,
+			&i.Task.Description,
+			&i.Task.Priority,
+			&i.Task.UserID,
+			&i.Task.Status,
+			&i.Task.CompletedAt,
+			&i.Task.ParentID,
+			&i.Task.SeriesID,
+			&i.TitleHighlight,
+			&i.DescriptionSnippet,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
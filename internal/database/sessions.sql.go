@@ -0,0 +1,207 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: sessions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const countActiveSessions = `-- name: CountActiveSessions :one
+SELECT COUNT(*) FROM sessions
+WHERE revoked_at IS NULL AND expires_at > ?
+`
+
+func (q *Queries) CountActiveSessions(ctx context.Context, expiresAt time.Time) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveSessions, expiresAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createSession = `-- name: CreateSession :one
+INSERT INTO sessions(id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at
+`
+
+type CreateSessionParams struct {
+	ID         string
+	UserID     string
+	DeviceName string
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.ID,
+		arg.UserID,
+		arg.DeviceName,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.CreatedAt,
+		arg.LastUsedAt,
+		arg.ExpiresAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DeviceName,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getActiveUserSessions = `-- name: GetActiveUserSessions :many
+SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at FROM sessions
+WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+ORDER BY last_used_at DESC
+`
+
+type GetActiveUserSessionsParams struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
+func (q *Queries) GetActiveUserSessions(ctx context.Context, arg GetActiveUserSessionsParams) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveUserSessions, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Session
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.DeviceName,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSessionByID = `-- name: GetSessionByID :one
+SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at FROM sessions WHERE id = ?
+`
+
+func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getSessionByID, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DeviceName,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const refreshSession = `-- name: RefreshSession :exec
+UPDATE sessions
+SET last_used_at = ?, expires_at = ?, user_agent = ?, ip_address = ?
+WHERE id = ?
+`
+
+type RefreshSessionParams struct {
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	UserAgent  string
+	IpAddress  string
+	ID         string
+}
+
+func (q *Queries) RefreshSession(ctx context.Context, arg RefreshSessionParams) error {
+	_, err := q.db.ExecContext(ctx, refreshSession,
+		arg.LastUsedAt,
+		arg.ExpiresAt,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.ID,
+	)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = ?
+WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	RevokedAt sql.NullTime
+	ID        string
+	UserID    string
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.RevokedAt, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = ?
+WHERE user_id = ? AND revoked_at IS NULL
+`
+
+type RevokeUserSessionsParams struct {
+	RevokedAt sql.NullTime
+	UserID    string
+}
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, arg RevokeUserSessionsParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserSessions, arg.RevokedAt, arg.UserID)
+	return err
+}
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE sessions SET last_used_at = ? WHERE id = ?
+`
+
+type TouchSessionParams struct {
+	LastUsedAt time.Time
+	ID         string
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.ExecContext(ctx, touchSession, arg.LastUsedAt, arg.ID)
+	return err
+}
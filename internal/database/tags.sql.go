@@ -0,0 +1,238 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: tags.sql
+
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const attachTagToTask = `-- name: AttachTagToTask :exec
+INSERT INTO task_tags(task_id, tag_id)
+VALUES (?, ?)
+ON CONFLICT (task_id, tag_id) DO NOTHING
+`
+
+type AttachTagToTaskParams struct {
+	TaskID string
+	TagID  string
+}
+
+func (q *Queries) AttachTagToTask(ctx context.Context, arg AttachTagToTaskParams) error {
+	_, err := q.db.ExecContext(ctx, attachTagToTask, arg.TaskID, arg.TagID)
+	return err
+}
+
+const createTag = `-- name: CreateTag :one
+INSERT INTO tags(id, created_at, updated_at, name, color, user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, created_at, updated_at, name, color, user_id
+`
+
+type CreateTagParams struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Color     string
+	UserID    string
+}
+
+func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, createTag,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Color,
+		arg.UserID,
+	)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Color,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const deleteTagByID = `-- name: DeleteTagByID :exec
+DELETE FROM tags WHERE id = ? AND user_id = ?
+`
+
+type DeleteTagByIDParams struct {
+	ID     string
+	UserID string
+}
+
+func (q *Queries) DeleteTagByID(ctx context.Context, arg DeleteTagByIDParams) error {
+	_, err := q.db.ExecContext(ctx, deleteTagByID, arg.ID, arg.UserID)
+	return err
+}
+
+const detachTagFromTask = `-- name: DetachTagFromTask :exec
+DELETE FROM task_tags WHERE task_id = ? AND tag_id = ?
+`
+
+type DetachTagFromTaskParams struct {
+	TaskID string
+	TagID  string
+}
+
+func (q *Queries) DetachTagFromTask(ctx context.Context, arg DetachTagFromTaskParams) error {
+	_, err := q.db.ExecContext(ctx, detachTagFromTask, arg.TaskID, arg.TagID)
+	return err
+}
+
+const getTagByID = `-- name: GetTagByID :one
+SELECT id, created_at, updated_at, name, color, user_id FROM tags WHERE id = ? AND user_id = ?
+`
+
+type GetTagByIDParams struct {
+	ID     string
+	UserID string
+}
+
+func (q *Queries) GetTagByID(ctx context.Context, arg GetTagByIDParams) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, getTagByID, arg.ID, arg.UserID)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Color,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getTagsForTasks = `-- name: GetTagsForTasks :many
+SELECT task_tags.task_id, tags.id, tags.created_at, tags.updated_at, tags.name, tags.color, tags.user_id
+FROM task_tags
+JOIN tags ON tags.id = task_tags.tag_id
+WHERE task_tags.task_id IN (/*SLICE:task_ids*/?)
+ORDER BY tags.name
+`
+
+type GetTagsForTasksRow struct {
+	TaskID string
+	Tag    Tag
+}
+
+func (q *Queries) GetTagsForTasks(ctx context.Context, taskIds []string) ([]GetTagsForTasksRow, error) {
+	query := getTagsForTasks
+	var queryParams []interface{}
+	if len(taskIds) > 0 {
+		for _, v := range taskIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:task_ids*/?", strings.Repeat(",?", len(taskIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:task_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForTasksRow
+	for rows.Next() {
+		var i GetTagsForTasksRow
+		if err := rows.Scan(
+			&i.TaskID,
+			&i.Tag.ID,
+			&i.Tag.CreatedAt,
+			&i.Tag.UpdatedAt,
+			&i.Tag.Name,
+			&i.Tag.Color,
+			&i.Tag.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersTags = `-- name: GetUsersTags :many
+SELECT id, created_at, updated_at, name, color, user_id FROM tags WHERE user_id = ? ORDER BY name
+`
+
+func (q *Queries) GetUsersTags(ctx context.Context, userID string) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersTags, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Color,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateTagByID = `-- name: UpdateTagByID :one
+UPDATE tags
+SET name = ?, color = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING id, created_at, updated_at, name, color, user_id
+`
+
+type UpdateTagByIDParams struct {
+	Name      string
+	Color     string
+	UpdatedAt time.Time
+	ID        string
+	UserID    string
+}
+
+func (q *Queries) UpdateTagByID(ctx context.Context, arg UpdateTagByIDParams) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, updateTagByID,
+		arg.Name,
+		arg.Color,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Color,
+		&i.UserID,
+	)
+	return i, err
+}
@@ -0,0 +1,380 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: task_reminders.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const claimDueReminders = `-- name: ClaimDueReminders :many
+UPDATE task_reminders
+SET status = 'sending', updated_at = ?1, claimed_at = ?1
+WHERE task_reminders.id IN (
+    SELECT due.id FROM task_reminders AS due
+    JOIN tasks ON tasks.id = due.task_id
+    WHERE due.status = 'pending'
+    AND due.remind_at <= ?1
+    AND tasks.status NOT IN ('done', 'cancelled')
+    AND (tasks.user_id = due.user_id OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = due.user_id))
+    ORDER BY due.remind_at
+    LIMIT ?2
+)
+RETURNING id, created_at, updated_at, task_id, user_id, minutes_before, channel, remind_at, status, attempts, last_error, claimed_at, sent_at
+`
+
+type ClaimDueRemindersParams struct {
+	Now       time.Time
+	BatchSize int64
+}
+
+func (q *Queries) ClaimDueReminders(ctx context.Context, arg ClaimDueRemindersParams) ([]TaskReminder, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueReminders, arg.Now, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaskReminder
+	for rows.Next() {
+		var i TaskReminder
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TaskID,
+			&i.UserID,
+			&i.MinutesBefore,
+			&i.Channel,
+			&i.RemindAt,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.ClaimedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createTaskReminder = `-- name: CreateTaskReminder :one
+INSERT INTO task_reminders(id, created_at, updated_at, task_id, user_id, minutes_before, channel, remind_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, created_at, updated_at, task_id, user_id, minutes_before, channel, remind_at, status, attempts, last_error, claimed_at, sent_at
+`
+
+type CreateTaskReminderParams struct {
+	ID            string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	TaskID        string
+	UserID        string
+	MinutesBefore int64
+	Channel       string
+	RemindAt      time.Time
+}
+
+func (q *Queries) CreateTaskReminder(ctx context.Context, arg CreateTaskReminderParams) (TaskReminder, error) {
+	row := q.db.QueryRowContext(ctx, createTaskReminder,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.TaskID,
+		arg.UserID,
+		arg.MinutesBefore,
+		arg.Channel,
+		arg.RemindAt,
+	)
+	var i TaskReminder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TaskID,
+		&i.UserID,
+		&i.MinutesBefore,
+		&i.Channel,
+		&i.RemindAt,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.ClaimedAt,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const deleteTaskReminder = `-- name: DeleteTaskReminder :exec
+DELETE FROM task_reminders WHERE id = ? AND task_id = ? AND user_id = ?
+`
+
+type DeleteTaskReminderParams struct {
+	ID     string
+	TaskID string
+	UserID string
+}
+
+func (q *Queries) DeleteTaskReminder(ctx context.Context, arg DeleteTaskReminderParams) error {
+	_, err := q.db.ExecContext(ctx, deleteTaskReminder, arg.ID, arg.TaskID, arg.UserID)
+	return err
+}
+
+const deleteUsersTaskReminders = `-- name: DeleteUsersTaskReminders :exec
+DELETE FROM task_reminders WHERE task_id = ? AND user_id = ?
+`
+
+type DeleteUsersTaskRemindersParams struct {
+	TaskID string
+	UserID string
+}
+
+func (q *Queries) DeleteUsersTaskReminders(ctx context.Context, arg DeleteUsersTaskRemindersParams) error {
+	_, err := q.db.ExecContext(ctx, deleteUsersTaskReminders, arg.TaskID, arg.UserID)
+	return err
+}
+
+const getAllTaskReminders = `-- name: GetAllTaskReminders :many
+SELECT id, created_at, updated_at, task_id, user_id, minutes_before, channel, remind_at, status, attempts, last_error, claimed_at, sent_at FROM task_reminders WHERE task_id = ?
+`
+
+func (q *Queries) GetAllTaskReminders(ctx context.Context, taskID string) ([]TaskReminder, error) {
+	rows, err := q.db.QueryContext(ctx, getAllTaskReminders, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaskReminder
+	for rows.Next() {
+		var i TaskReminder
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TaskID,
+			&i.UserID,
+			&i.MinutesBefore,
+			&i.Channel,
+			&i.RemindAt,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.ClaimedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReminderDeliveries = `-- name: GetReminderDeliveries :many
+SELECT task_reminders.id, task_reminders.channel, task_reminders.remind_at,
+    tasks.id AS task_id, tasks.title, tasks.description, tasks.due_until,
+    users.id AS user_id, users.username, users.email
+FROM task_reminders
+JOIN tasks ON tasks.id = task_reminders.task_id
+JOIN users ON users.id = task_reminders.user_id
+WHERE task_reminders.id IN (/*SLICE:ids*/?)
+AND (tasks.user_id = users.id OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = users.id))
+`
+
+type GetReminderDeliveriesRow struct {
+	ID          string
+	Channel     string
+	RemindAt    time.Time
+	TaskID      string
+	Title       string
+	Description string
+	DueUntil    time.Time
+	UserID      string
+	Username    string
+	Email       string
+}
+
+func (q *Queries) GetReminderDeliveries(ctx context.Context, ids []string) ([]GetReminderDeliveriesRow, error) {
+	query := getReminderDeliveries
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReminderDeliveriesRow
+	for rows.Next() {
+		var i GetReminderDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Channel,
+			&i.RemindAt,
+			&i.TaskID,
+			&i.Title,
+			&i.Description,
+			&i.DueUntil,
+			&i.UserID,
+			&i.Username,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTaskReminders = `-- name: GetTaskReminders :many
+SELECT id, created_at, updated_at, task_id, user_id, minutes_before, channel, remind_at, status, attempts, last_error, claimed_at, sent_at FROM task_reminders
+WHERE task_id = ? AND user_id = ?
+ORDER BY remind_at
+`
+
+type GetTaskRemindersParams struct {
+	TaskID string
+	UserID string
+}
+
+func (q *Queries) GetTaskReminders(ctx context.Context, arg GetTaskRemindersParams) ([]TaskReminder, error) {
+	rows, err := q.db.QueryContext(ctx, getTaskReminders, arg.TaskID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaskReminder
+	for rows.Next() {
+		var i TaskReminder
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TaskID,
+			&i.UserID,
+			&i.MinutesBefore,
+			&i.Channel,
+			&i.RemindAt,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.ClaimedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markReminderFailed = `-- name: MarkReminderFailed :exec
+UPDATE task_reminders
+SET status = ?1, attempts = attempts + 1, last_error = ?2, updated_at = ?3
+WHERE id = ?4
+`
+
+type MarkReminderFailedParams struct {
+	Status    string
+	LastError string
+	UpdatedAt time.Time
+	ID        string
+}
+
+func (q *Queries) MarkReminderFailed(ctx context.Context, arg MarkReminderFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markReminderFailed,
+		arg.Status,
+		arg.LastError,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const markReminderSent = `-- name: MarkReminderSent :exec
+UPDATE task_reminders
+SET status = 'sent', sent_at = ?, updated_at = ?
+WHERE id = ?
+`
+
+type MarkReminderSentParams struct {
+	SentAt    sql.NullTime
+	UpdatedAt time.Time
+	ID        string
+}
+
+func (q *Queries) MarkReminderSent(ctx context.Context, arg MarkReminderSentParams) error {
+	_, err := q.db.ExecContext(ctx, markReminderSent, arg.SentAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const releaseStaleReminderClaims = `-- name: ReleaseStaleReminderClaims :execrows
+UPDATE task_reminders
+SET status = 'pending', updated_at = ?1, claimed_at = NULL
+WHERE status = 'sending' AND claimed_at < ?2
+`
+
+type ReleaseStaleReminderClaimsParams struct {
+	Now           time.Time
+	ClaimedBefore sql.NullTime
+}
+
+func (q *Queries) ReleaseStaleReminderClaims(ctx context.Context, arg ReleaseStaleReminderClaimsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, releaseStaleReminderClaims, arg.Now, arg.ClaimedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rescheduleTaskReminder = `-- name: RescheduleTaskReminder :exec
+UPDATE task_reminders
+SET remind_at = ?, status = 'pending', attempts = 0, last_error = '', claimed_at = NULL, sent_at = NULL, updated_at = ?
+WHERE id = ?
+`
+
+type RescheduleTaskReminderParams struct {
+	RemindAt  time.Time
+	UpdatedAt time.Time
+	ID        string
+}
+
+func (q *Queries) RescheduleTaskReminder(ctx context.Context, arg RescheduleTaskReminderParams) error {
+	_, err := q.db.ExecContext(ctx, rescheduleTaskReminder, arg.RemindAt, arg.UpdatedAt, arg.ID)
+	return err
+}
@@ -0,0 +1,256 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: task_series.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const copyTaskTags = `-- name: CopyTaskTags :exec
+INSERT INTO task_tags(task_id, tag_id)
+SELECT ?1, task_tags.tag_id FROM task_tags
+WHERE task_tags.task_id = ?2
+`
+
+type CopyTaskTagsParams struct {
+	NewTaskID string
+	TaskID    string
+}
+
+func (q *Queries) CopyTaskTags(ctx context.Context, arg CopyTaskTagsParams) error {
+	_, err := q.db.ExecContext(ctx, copyTaskTags, arg.NewTaskID, arg.TaskID)
+	return err
+}
+
+const createTaskSeries = `-- name: CreateTaskSeries :one
+INSERT INTO task_series(id, created_at, updated_at, rule, starts_at, title, description, priority, user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, created_at, updated_at, rule, starts_at, title, description, priority, user_id
+`
+
+type CreateTaskSeriesParams struct {
+	ID          string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Rule        string
+	StartsAt    time.Time
+	Title       string
+	Description string
+	Priority    int64
+	UserID      string
+}
+
+func (q *Queries) CreateTaskSeries(ctx context.Context, arg CreateTaskSeriesParams) (TaskSeries, error) {
+	row := q.db.QueryRowContext(ctx, createTaskSeries,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Rule,
+		arg.StartsAt,
+		arg.Title,
+		arg.Description,
+		arg.Priority,
+		arg.UserID,
+	)
+	var i TaskSeries
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Rule,
+		&i.StartsAt,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getSeriesOccurrence = `-- name: GetSeriesOccurrence :one
+SELECT seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id FROM tasks WHERE series_id = ? AND due_until = ?
+`
+
+type GetSeriesOccurrenceParams struct {
+	SeriesID sql.NullString
+	DueUntil time.Time
+}
+
+func (q *Queries) GetSeriesOccurrence(ctx context.Context, arg GetSeriesOccurrenceParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, getSeriesOccurrence, arg.SeriesID, arg.DueUntil)
+	var i Task
+	err := row.Scan(
+		&i.Seq,
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DueUntil,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
+	)
+	return i, err
+}
+
+const getTaskSeriesByID = `-- name: GetTaskSeriesByID :one
+SELECT id, created_at, updated_at, rule, starts_at, title, description, priority, user_id FROM task_series WHERE id = ?
+`
+
+func (q *Queries) GetTaskSeriesByID(ctx context.Context, id string) (TaskSeries, error) {
+	row := q.db.QueryRowContext(ctx, getTaskSeriesByID, id)
+	var i TaskSeries
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Rule,
+		&i.StartsAt,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getTaskSeriesByIDs = `-- name: GetTaskSeriesByIDs :many
+SELECT id, created_at, updated_at, rule, starts_at, title, description, priority, user_id FROM task_series WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) GetTaskSeriesByIDs(ctx context.Context, ids []string) ([]TaskSeries, error) {
+	query := getTaskSeriesByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaskSeries
+	for rows.Next() {
+		var i TaskSeries
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Rule,
+			&i.StartsAt,
+			&i.Title,
+			&i.Description,
+			&i.Priority,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setTaskSeries = `-- name: SetTaskSeries :one
+UPDATE tasks
+SET series_id = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+`
+
+type SetTaskSeriesParams struct {
+	SeriesID  sql.NullString
+	UpdatedAt time.Time
+	ID        string
+	UserID    string
+}
+
+func (q *Queries) SetTaskSeries(ctx context.Context, arg SetTaskSeriesParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, setTaskSeries,
+		arg.SeriesID,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.Seq,
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DueUntil,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
+	)
+	return i, err
+}
+
+const updateTaskSeriesByID = `-- name: UpdateTaskSeriesByID :one
+UPDATE task_series
+SET rule = ?, starts_at = ?, title = ?, description = ?, priority = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING id, created_at, updated_at, rule, starts_at, title, description, priority, user_id
+`
+
+type UpdateTaskSeriesByIDParams struct {
+	Rule        string
+	StartsAt    time.Time
+	Title       string
+	Description string
+	Priority    int64
+	UpdatedAt   time.Time
+	ID          string
+	UserID      string
+}
+
+func (q *Queries) UpdateTaskSeriesByID(ctx context.Context, arg UpdateTaskSeriesByIDParams) (TaskSeries, error) {
+	row := q.db.QueryRowContext(ctx, updateTaskSeriesByID,
+		arg.Rule,
+		arg.StartsAt,
+		arg.Title,
+		arg.Description,
+		arg.Priority,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.UserID,
+	)
+	var i TaskSeries
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Rule,
+		&i.StartsAt,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+	)
+	return i, err
+}
@@ -7,13 +7,55 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
+const completeTaskByID = `-- name: CompleteTaskByID :one
+UPDATE tasks
+SET status = 'done', completed_at = ?, updated_at = ?
+WHERE id = ?3
+AND (tasks.user_id = ?4 OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = ?4))
+RETURNING seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+`
+
+type CompleteTaskByIDParams struct {
+	CompletedAt sql.NullTime
+	UpdatedAt   time.Time
+	ID          string
+	UserID      string
+}
+
+func (q *Queries) CompleteTaskByID(ctx context.Context, arg CompleteTaskByIDParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, completeTaskByID,
+		arg.CompletedAt,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.Seq,
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DueUntil,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
+	)
+	return i, err
+}
+
 const createTask = `-- name: CreateTask :one
-INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, category, user_id)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-RETURNING id, created_at, updated_at, due_until, title, description, priority, category, user_id
+INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, user_id, parent_id, series_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
 `
 
 type CreateTaskParams struct {
@@ -24,8 +66,9 @@ type CreateTaskParams struct {
 	Title       string
 	Description string
 	Priority    int64
-	Category    string
 	UserID      string
+	ParentID    sql.NullString
+	SeriesID    sql.NullString
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
@@ -37,11 +80,13 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		arg.Title,
 		arg.Description,
 		arg.Priority,
-		arg.Category,
 		arg.UserID,
+		arg.ParentID,
+		arg.SeriesID,
 	)
 	var i Task
 	err := row.Scan(
+		&i.Seq,
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -49,12 +94,39 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.Title,
 		&i.Description,
 		&i.Priority,
-		&i.Category,
 		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
 	)
 	return i, err
 }
 
+const createTaskShare = `-- name: CreateTaskShare :exec
+INSERT INTO task_shares(task_id, user_id, created_at)
+SELECT tasks.id, ?1, ?2 FROM tasks
+WHERE tasks.id = ?3 AND tasks.user_id = ?4
+ON CONFLICT (task_id, user_id) DO NOTHING
+`
+
+type CreateTaskShareParams struct {
+	SharedWithID string
+	CreatedAt    time.Time
+	TaskID       string
+	OwnerID      string
+}
+
+func (q *Queries) CreateTaskShare(ctx context.Context, arg CreateTaskShareParams) error {
+	_, err := q.db.ExecContext(ctx, createTaskShare,
+		arg.SharedWithID,
+		arg.CreatedAt,
+		arg.TaskID,
+		arg.OwnerID,
+	)
+	return err
+}
+
 const deleteTaskByID = `-- name: DeleteTaskByID :exec
 DELETE FROM tasks WHERE id = ? AND user_id = ?
 `
@@ -69,33 +141,45 @@ func (q *Queries) DeleteTaskByID(ctx context.Context, arg DeleteTaskByIDParams)
 	return err
 }
 
-const getAllUsersTasks = `-- name: GetAllUsersTasks :many
-SELECT id, created_at, updated_at, due_until, title, description, priority, category, user_id FROM tasks WHERE user_id = ?
+const deleteTaskShare = `-- name: DeleteTaskShare :exec
+DELETE FROM task_shares
+WHERE task_shares.task_id = ?1 AND task_shares.user_id = ?2
+AND task_shares.task_id IN (SELECT tasks.id FROM tasks WHERE tasks.user_id = ?3)
+`
+
+type DeleteTaskShareParams struct {
+	TaskID       string
+	SharedWithID string
+	OwnerID      string
+}
+
+func (q *Queries) DeleteTaskShare(ctx context.Context, arg DeleteTaskShareParams) error {
+	_, err := q.db.ExecContext(ctx, deleteTaskShare, arg.TaskID, arg.SharedWithID, arg.OwnerID)
+	return err
+}
+
+const getTaskAncestorIDs = `-- name: GetTaskAncestorIDs :many
+WITH RECURSIVE ancestors(id, parent_id) AS (
+    SELECT tasks.id, tasks.parent_id FROM tasks WHERE tasks.id = ?
+    UNION ALL
+    SELECT tasks.id, tasks.parent_id FROM tasks JOIN ancestors ON tasks.id = ancestors.parent_id
+)
+SELECT ancestors.id FROM ancestors
 `
 
-func (q *Queries) GetAllUsersTasks(ctx context.Context, userID string) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, getAllUsersTasks, userID)
+func (q *Queries) GetTaskAncestorIDs(ctx context.Context, id string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getTaskAncestorIDs, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Task
+	var items []string
 	for rows.Next() {
-		var i Task
-		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DueUntil,
-			&i.Title,
-			&i.Description,
-			&i.Priority,
-			&i.Category,
-			&i.UserID,
-		); err != nil {
+		var id string
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, id)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -107,13 +191,21 @@ func (q *Queries) GetAllUsersTasks(ctx context.Context, userID string) ([]Task,
 }
 
 const getTaskByID = `-- name: GetTaskByID :one
-SELECT id, created_at, updated_at, due_until, title, description, priority, category, user_id FROM tasks WHERE id = ?
+SELECT seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id FROM tasks
+WHERE id = ?1
+AND (tasks.user_id = ?2 OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = ?2))
 `
 
-func (q *Queries) GetTaskByID(ctx context.Context, id string) (Task, error) {
-	row := q.db.QueryRowContext(ctx, getTaskByID, id)
+type GetTaskByIDParams struct {
+	ID     string
+	UserID string
+}
+
+func (q *Queries) GetTaskByID(ctx context.Context, arg GetTaskByIDParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, getTaskByID, arg.ID, arg.UserID)
 	var i Task
 	err := row.Scan(
+		&i.Seq,
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -121,40 +213,51 @@ func (q *Queries) GetTaskByID(ctx context.Context, id string) (Task, error) {
 		&i.Title,
 		&i.Description,
 		&i.Priority,
-		&i.Category,
 		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
 	)
 	return i, err
 }
 
-const getTaskByTitleAndDescription = `-- name: GetTaskByTitleAndDescription :many
-SELECT id, created_at, updated_at, due_until, title, description, priority, category, user_id FROM tasks WHERE title LIKE ? OR description LIKE ?
+const getTaskShares = `-- name: GetTaskShares :many
+SELECT task_shares.task_id, task_shares.created_at, users.id AS user_id, users.username, users.email
+FROM task_shares
+JOIN tasks ON tasks.id = task_shares.task_id
+JOIN users ON users.id = task_shares.user_id
+WHERE task_shares.task_id = ? AND tasks.user_id = ?
 `
 
-type GetTaskByTitleAndDescriptionParams struct {
-	Title       string
-	Description string
+type GetTaskSharesParams struct {
+	TaskID string
+	UserID string
+}
+
+type GetTaskSharesRow struct {
+	TaskID    string
+	CreatedAt time.Time
+	UserID    string
+	Username  string
+	Email     string
 }
 
-func (q *Queries) GetTaskByTitleAndDescription(ctx context.Context, arg GetTaskByTitleAndDescriptionParams) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, getTaskByTitleAndDescription, arg.Title, arg.Description)
+func (q *Queries) GetTaskShares(ctx context.Context, arg GetTaskSharesParams) ([]GetTaskSharesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTaskShares, arg.TaskID, arg.UserID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Task
+	var items []GetTaskSharesRow
 	for rows.Next() {
-		var i Task
+		var i GetTaskSharesRow
 		if err := rows.Scan(
-			&i.ID,
+			&i.TaskID,
 			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DueUntil,
-			&i.Title,
-			&i.Description,
-			&i.Priority,
-			&i.Category,
 			&i.UserID,
+			&i.Username,
+			&i.Email,
 		); err != nil {
 			return nil, err
 		}
@@ -169,12 +272,24 @@ func (q *Queries) GetTaskByTitleAndDescription(ctx context.Context, arg GetTaskB
 	return items, nil
 }
 
-const getTasksByDescription = `-- name: GetTasksByDescription :many
-SELECT id, created_at, updated_at, due_until, title, description, priority, category, user_id FROM tasks WHERE description LIKE ?
+const getTaskTree = `-- name: GetTaskTree :many
+WITH RECURSIVE tree(id) AS (
+    SELECT tasks.id FROM tasks WHERE tasks.id = ?1 AND tasks.user_id = ?2
+    UNION ALL
+    SELECT tasks.id FROM tasks JOIN tree ON tasks.parent_id = tree.id
+)
+SELECT tasks.seq, tasks.id, tasks.created_at, tasks.updated_at, tasks.due_until, tasks.title, tasks.description, tasks.priority, tasks.user_id, tasks.status, tasks.completed_at, tasks.parent_id, tasks.series_id FROM tasks
+WHERE tasks.id IN (SELECT tree.id FROM tree)
+ORDER BY tasks.created_at, tasks.id
 `
 
-func (q *Queries) GetTasksByDescription(ctx context.Context, description string) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, getTasksByDescription, description)
+type GetTaskTreeParams struct {
+	ID     string
+	UserID string
+}
+
+func (q *Queries) GetTaskTree(ctx context.Context, arg GetTaskTreeParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, getTaskTree, arg.ID, arg.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -183,6 +298,7 @@ func (q *Queries) GetTasksByDescription(ctx context.Context, description string)
 	for rows.Next() {
 		var i Task
 		if err := rows.Scan(
+			&i.Seq,
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
@@ -190,8 +306,11 @@ func (q *Queries) GetTasksByDescription(ctx context.Context, description string)
 			&i.Title,
 			&i.Description,
 			&i.Priority,
-			&i.Category,
 			&i.UserID,
+			&i.Status,
+			&i.CompletedAt,
+			&i.ParentID,
+			&i.SeriesID,
 		); err != nil {
 			return nil, err
 		}
@@ -206,58 +325,122 @@ func (q *Queries) GetTasksByDescription(ctx context.Context, description string)
 	return items, nil
 }
 
-const getTasksByTitle = `-- name: GetTasksByTitle :many
-SELECT id, created_at, updated_at, due_until, title, description, priority, category, user_id FROM tasks WHERE title LIKE ?
+const reopenTaskByID = `-- name: ReopenTaskByID :one
+UPDATE tasks
+SET status = 'todo', completed_at = NULL, updated_at = ?
+WHERE id = ?2
+AND (tasks.user_id = ?3 OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = ?3))
+RETURNING seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
 `
 
-func (q *Queries) GetTasksByTitle(ctx context.Context, title string) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, getTasksByTitle, title)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Task
-	for rows.Next() {
-		var i Task
-		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DueUntil,
-			&i.Title,
-			&i.Description,
-			&i.Priority,
-			&i.Category,
-			&i.UserID,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type ReopenTaskByIDParams struct {
+	UpdatedAt time.Time
+	ID        string
+	UserID    string
+}
+
+func (q *Queries) ReopenTaskByID(ctx context.Context, arg ReopenTaskByIDParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, reopenTaskByID, arg.UpdatedAt, arg.ID, arg.UserID)
+	var i Task
+	err := row.Scan(
+		&i.Seq,
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DueUntil,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
+	)
+	return i, err
+}
+
+const reparentSubtasks = `-- name: ReparentSubtasks :exec
+UPDATE tasks
+SET parent_id = ?1, updated_at = ?2
+WHERE parent_id = ?3 AND user_id = ?4
+`
+
+type ReparentSubtasksParams struct {
+	NewParentID sql.NullString
+	UpdatedAt   time.Time
+	ParentID    sql.NullString
+	UserID      string
+}
+
+func (q *Queries) ReparentSubtasks(ctx context.Context, arg ReparentSubtasksParams) error {
+	_, err := q.db.ExecContext(ctx, reparentSubtasks,
+		arg.NewParentID,
+		arg.UpdatedAt,
+		arg.ParentID,
+		arg.UserID,
+	)
+	return err
+}
+
+const setTaskParent = `-- name: SetTaskParent :one
+UPDATE tasks
+SET parent_id = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+`
+
+type SetTaskParentParams struct {
+	ParentID  sql.NullString
+	UpdatedAt time.Time
+	ID        string
+	UserID    string
+}
+
+func (q *Queries) SetTaskParent(ctx context.Context, arg SetTaskParentParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, setTaskParent,
+		arg.ParentID,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.Seq,
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DueUntil,
+		&i.Title,
+		&i.Description,
+		&i.Priority,
+		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
+	)
+	return i, err
 }
 
 const updateTaskByID = `-- name: UpdateTaskByID :one
 UPDATE tasks
-SET title = ?, description = ?, priority = ?, category = ?, updated_at = ?, due_until = ?
-WHERE id = ?
-RETURNING id, created_at, updated_at, due_until, title, description, priority, category, user_id
+SET title = ?, description = ?, priority = ?, updated_at = ?, due_until = ?, status = ?, completed_at = ?
+WHERE id = ?8
+AND (tasks.user_id = ?9 OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = ?9))
+RETURNING seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
 `
 
 type UpdateTaskByIDParams struct {
 	Title       string
 	Description string
 	Priority    int64
-	Category    string
 	UpdatedAt   time.Time
 	DueUntil    time.Time
+	Status      string
+	CompletedAt sql.NullTime
 	ID          string
+	UserID      string
 }
 
 func (q *Queries) UpdateTaskByID(ctx context.Context, arg UpdateTaskByIDParams) (Task, error) {
@@ -265,13 +448,16 @@ func (q *Queries) UpdateTaskByID(ctx context.Context, arg UpdateTaskByIDParams)
 		arg.Title,
 		arg.Description,
 		arg.Priority,
-		arg.Category,
 		arg.UpdatedAt,
 		arg.DueUntil,
+		arg.Status,
+		arg.CompletedAt,
 		arg.ID,
+		arg.UserID,
 	)
 	var i Task
 	err := row.Scan(
+		&i.Seq,
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -279,8 +465,11 @@ func (q *Queries) UpdateTaskByID(ctx context.Context, arg UpdateTaskByIDParams)
 		&i.Title,
 		&i.Description,
 		&i.Priority,
-		&i.Category,
 		&i.UserID,
+		&i.Status,
+		&i.CompletedAt,
+		&i.ParentID,
+		&i.SeriesID,
 	)
 	return i, err
 }
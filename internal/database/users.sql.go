@@ -7,20 +7,95 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
-const addAdminPrivilages = `-- name: AddAdminPrivilages :one
-UPDATE users SET is_admin = TRUE, updated_at = ? WHERE id = ? RETURNING id, created_at, updated_at, email, username, hashed_password, is_admin
+const createUser = `-- name: CreateUser :exec
+INSERT INTO users(id, created_at, updated_at, email, username, hashed_password)
+VALUES (?, ?, ?, ?, ?, ?)
 `
 
-type AddAdminPrivilagesParams struct {
+type CreateUserParams struct {
+	ID             string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Email          string
+	Username       string
+	HashedPassword string
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
+	_, err := q.db.ExecContext(ctx, createUser,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Email,
+		arg.Username,
+		arg.HashedPassword,
+	)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE users.id = ?1
+AND (
+    NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin')
+    OR (
+        SELECT COUNT(*) FROM user_roles AS admin_roles
+        JOIN users AS admins ON admins.id = admin_roles.user_id
+        WHERE admin_roles.role = 'admin' AND admins.disabled_at IS NULL AND admins.id != ?1
+    ) > 0
+)
+`
+
+// Like DisableUser this keeps the last active admin, no row is deleted then.
+func (q *Queries) DeleteUser(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disableTOTP = `-- name: DisableTOTP :exec
+UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = ? WHERE id = ?
+`
+
+type DisableTOTPParams struct {
 	UpdatedAt time.Time
 	ID        string
 }
 
-func (q *Queries) AddAdminPrivilages(ctx context.Context, arg AddAdminPrivilagesParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, addAdminPrivilages, arg.UpdatedAt, arg.ID)
+func (q *Queries) DisableTOTP(ctx context.Context, arg DisableTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, disableTOTP, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const disableUser = `-- name: DisableUser :one
+UPDATE users SET disabled_at = ?1, updated_at = ?2
+WHERE users.id = ?3
+AND (
+    NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin')
+    OR (
+        SELECT COUNT(*) FROM user_roles AS admin_roles
+        JOIN users AS admins ON admins.id = admin_roles.user_id
+        WHERE admin_roles.role = 'admin' AND admins.disabled_at IS NULL AND admins.id != ?3
+    ) > 0
+)
+RETURNING id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at
+`
+
+type DisableUserParams struct {
+	DisabledAt sql.NullTime
+	UpdatedAt  time.Time
+	ID         string
+}
+
+// The last active admin can't be disabled, no row is returned then.
+func (q *Queries) DisableUser(ctx context.Context, arg DisableUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableUser, arg.DisabledAt, arg.UpdatedAt, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -29,39 +104,68 @@ func (q *Queries) AddAdminPrivilages(ctx context.Context, arg AddAdminPrivilages
 		&i.Email,
 		&i.Username,
 		&i.HashedPassword,
-		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
-const createUser = `-- name: CreateUser :exec
-INSERT INTO users(id, created_at, updated_at, email, username, hashed_password)
-VALUES (?, ?, ?, ?, ?, ?)
+const enableTOTP = `-- name: EnableTOTP :exec
+UPDATE users SET totp_enabled_at = ?, totp_last_step = ?, updated_at = ? WHERE id = ?
 `
 
-type CreateUserParams struct {
-	ID             string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	Username       string
-	HashedPassword string
+type EnableTOTPParams struct {
+	TotpEnabledAt sql.NullTime
+	TotpLastStep  int64
+	UpdatedAt     time.Time
+	ID            string
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
-	_, err := q.db.ExecContext(ctx, createUser,
-		arg.ID,
-		arg.CreatedAt,
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableTOTP,
+		arg.TotpEnabledAt,
+		arg.TotpLastStep,
 		arg.UpdatedAt,
-		arg.Email,
-		arg.Username,
-		arg.HashedPassword,
+		arg.ID,
 	)
 	return err
 }
 
+const enableUser = `-- name: EnableUser :one
+UPDATE users SET disabled_at = NULL, updated_at = ? WHERE id = ? RETURNING id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at
+`
+
+type EnableUserParams struct {
+	UpdatedAt time.Time
+	ID        string
+}
+
+func (q *Queries) EnableUser(ctx context.Context, arg EnableUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUser, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Username,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, username, hashed_password, is_admin FROM users WHERE email = ?
+SELECT id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at FROM users WHERE email = ?
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -74,13 +178,18 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.Username,
 		&i.HashedPassword,
-		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, username, hashed_password, is_admin FROM users WHERE id = ?
+SELECT id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at FROM users WHERE id = ?
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
@@ -93,13 +202,50 @@ func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
 		&i.Email,
 		&i.Username,
 		&i.HashedPassword,
-		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
+const getUserTaskCounts = `-- name: GetUserTaskCounts :many
+SELECT status, COUNT(*) AS count FROM tasks WHERE user_id = ? GROUP BY status
+`
+
+type GetUserTaskCountsRow struct {
+	Status string
+	Count  int64
+}
+
+func (q *Queries) GetUserTaskCounts(ctx context.Context, userID string) ([]GetUserTaskCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserTaskCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserTaskCountsRow
+	for rows.Next() {
+		var i GetUserTaskCountsRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, email, username, hashed_password, is_admin FROM users
+SELECT id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -118,7 +264,12 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.Email,
 			&i.Username,
 			&i.HashedPassword,
-			&i.IsAdmin,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.DisabledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -133,17 +284,85 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
-const revokeAdminPrivilages = `-- name: RevokeAdminPrivilages :one
-UPDATE users SET is_admin = FALSE, updated_at = ? WHERE id = ? RETURNING id, created_at, updated_at, email, username, hashed_password, is_admin
+const listUsers = `-- name: ListUsers :many
+SELECT users.id, users.created_at, users.updated_at, users.email, users.username, users.hashed_password, users.email_verified_at, users.pending_email, users.totp_secret, users.totp_enabled_at, users.totp_last_step, users.disabled_at, (SELECT COUNT(*) FROM tasks WHERE tasks.user_id = users.id) AS task_count
+FROM users
+WHERE instr(lower(users.username), lower(?1)) > 0 OR instr(lower(users.email), lower(?1)) > 0
+ORDER BY users.created_at, users.id
+LIMIT ?3 OFFSET ?2
 `
 
-type RevokeAdminPrivilagesParams struct {
-	UpdatedAt time.Time
-	ID        string
+type ListUsersParams struct {
+	Query  string
+	Offset int64
+	Limit  int64
 }
 
-func (q *Queries) RevokeAdminPrivilages(ctx context.Context, arg RevokeAdminPrivilagesParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, revokeAdminPrivilages, arg.UpdatedAt, arg.ID)
+type ListUsersRow struct {
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	Username        string
+	HashedPassword  string
+	EmailVerifiedAt sql.NullTime
+	PendingEmail    sql.NullString
+	TotpSecret      sql.NullString
+	TotpEnabledAt   sql.NullTime
+	TotpLastStep    int64
+	DisabledAt      sql.NullTime
+	TaskCount       int64
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Query, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.Username,
+			&i.HashedPassword,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.DisabledAt,
+			&i.TaskCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPendingEmail = `-- name: SetPendingEmail :one
+UPDATE users SET pending_email = ?, updated_at = ? WHERE id = ? RETURNING id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at
+`
+
+type SetPendingEmailParams struct {
+	PendingEmail sql.NullString
+	UpdatedAt    time.Time
+	ID           string
+}
+
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setPendingEmail, arg.PendingEmail, arg.UpdatedAt, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -152,16 +371,36 @@ func (q *Queries) RevokeAdminPrivilages(ctx context.Context, arg RevokeAdminPriv
 		&i.Email,
 		&i.Username,
 		&i.HashedPassword,
-		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
+const setTOTPSecret = `-- name: SetTOTPSecret :exec
+UPDATE users SET totp_secret = ?, totp_enabled_at = NULL, totp_last_step = 0, updated_at = ? WHERE id = ?
+`
+
+type SetTOTPSecretParams struct {
+	TotpSecret sql.NullString
+	UpdatedAt  time.Time
+	ID         string
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setTOTPSecret, arg.TotpSecret, arg.UpdatedAt, arg.ID)
+	return err
+}
+
 const updateUserByID = `-- name: UpdateUserByID :one
 UPDATE users
 SET email = ?, username = ?, hashed_password = ?, updated_at = ?
 WHERE id = ?
-RETURNING id, created_at, updated_at, email, username, hashed_password, is_admin
+RETURNING id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at
 `
 
 type UpdateUserByIDParams struct {
@@ -188,7 +427,86 @@ func (q *Queries) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams)
 		&i.Email,
 		&i.Username,
 		&i.HashedPassword,
-		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = ?, updated_at = ? WHERE id = ?
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string
+	UpdatedAt      time.Time
+	ID             string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE users SET totp_last_step = ?1 WHERE id = ?2 AND totp_last_step < ?1
+`
+
+type UseTOTPStepParams struct {
+	Step int64
+	ID   string
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPStep, arg.Step, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email = ?1,
+    pending_email = CASE WHEN pending_email = ?1 THEN NULL ELSE pending_email END,
+    email_verified_at = ?2,
+    updated_at = ?3
+WHERE id = ?4
+RETURNING id, created_at, updated_at, email, username, hashed_password, email_verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, disabled_at
+`
+
+type VerifyUserEmailParams struct {
+	Email           string
+	EmailVerifiedAt sql.NullTime
+	UpdatedAt       time.Time
+	ID              string
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail,
+		arg.Email,
+		arg.EmailVerifiedAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Username,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.DisabledAt,
 	)
 	return i, err
 }
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := mail.FileMailer{From: "taskin@test.com", Dir: dir}
+
+	err := mailer.Send(context.Background(), testMessage)
+	assert.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: user@test.com\r\n")
+	assert.Contains(t, string(data), "transfer to landlord")
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := mail.LogMailer{Logger: log.New(&buf, "", 0)}
+
+	err := mailer.Send(context.Background(), testMessage)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "user@test.com")
+	assert.Contains(t, buf.String(), "transfer to landlord")
+}
@@ -0,0 +1,145 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSMTPMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// startFakeSMTPServer accepts a single SMTP session and sends what it received on the channel.
+func startFakeSMTPServer(t *testing.T) (string, <-chan fakeSMTPMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldnt start fake smtp server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan fakeSMTPMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var msg fakeSMTPMessage
+		reply("220 localhost fake smtp")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				msg.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 ok")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 ok")
+			case command == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				msg.Data = data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				messages <- msg
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+var testMessage = mail.Message{
+	To:      "user@test.com",
+	Subject: "Reminder: pay rent",
+	Body:    "transfer to landlord",
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, messages := startFakeSMTPServer(t)
+
+	mailer, err := mail.NewSMTPMailer(addr, "taskin@test.com", "", "")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = mailer.Send(ctx, testMessage)
+	assert.NoError(t, err)
+
+	msg := <-messages
+	assert.Equal(t, "taskin@test.com", msg.From)
+	assert.Equal(t, []string{"user@test.com"}, msg.To)
+	assert.Contains(t, msg.Data, "Subject: Reminder: pay rent\r\n")
+	assert.Contains(t, msg.Data, "transfer to landlord")
+}
+
+func TestSMTPMailerHeaderInjection(t *testing.T) {
+	addr, messages := startFakeSMTPServer(t)
+
+	mailer, err := mail.NewSMTPMailer(addr, "taskin@test.com", "", "")
+	assert.NoError(t, err)
+
+	msg := testMessage
+	msg.Subject = "pay rent\r\nBcc: someone@else.com"
+
+	err = mailer.Send(context.Background(), msg)
+	assert.NoError(t, err)
+
+	received := <-messages
+	headers, _, _ := strings.Cut(received.Data, "\r\n\r\n")
+	assert.NotContains(t, headers, "\r\nBcc:")
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	mailer, err := mail.NewSMTPMailer(addr, "taskin@test.com", "", "")
+	assert.NoError(t, err)
+
+	err = mailer.Send(context.Background(), testMessage)
+	assert.Error(t, err)
+}
+
+func TestNewSMTPMailerInvalidAddr(t *testing.T) {
+	_, err := mail.NewSMTPMailer("localhost", "taskin@test.com", "", "")
+	assert.Error(t, err)
+}
@@ -1,61 +1,229 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"log"
-	"net/http"
 	"os"
-	"strings"
+	"runtime/debug"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/config"
 	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/magicznykacpur/taskin-backend/metrics"
+	"github.com/magicznykacpur/taskin-backend/notify"
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+	"github.com/magicznykacpur/taskin-backend/reminders"
+	"github.com/magicznykacpur/taskin-backend/tracing"
 	_ "modernc.org/sqlite"
 )
 
-func loadEnvVars() {
-	data, err := os.ReadFile(".env")
-	if err != nil {
-		log.Fatalf("coudlnt open env file: %v", err)
+// version and commit are set at build time with
+// -ldflags "-X main.version=1.2.3 -X main.commit=abc123".
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// buildCommit falls back to the vcs revision go build stamps into the binary.
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return ""
+}
+
+func loadMailer(cfg config.Mail) mail.Mailer {
+	if cfg.SMTPAddr != "" {
+		mailer, err := mail.NewSMTPMailer(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword)
+		if err != nil {
+			log.Fatalf("couldnt configure smtp mailer: %v", err)
+		}
+		return mailer
+	}
+
+	if cfg.Dir != "" {
+		return &mail.FileMailer{From: cfg.From, Dir: cfg.Dir}
+	}
+
+	return &mail.LogMailer{From: cfg.From}
+}
+
+func loadNotifiers(cfg config.Reminders, mailer mail.Mailer) map[string]notify.Notifier {
+	notifiers := map[string]notify.Notifier{
+		notify.ChannelEmail: &notify.EmailNotifier{Mailer: mailer},
 	}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		parts := strings.Split(line, "=")
-		os.Setenv(parts[0], parts[1])
+	if cfg.WebhookURL != "" {
+		notifiers[notify.ChannelWebhook] = notify.NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret)
 	}
+
+	return notifiers
 }
 
 func main() {
-	loadEnvVars()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	appConfig := loadConfig(os.Args[1:])
 
-	db, err := sql.Open("sqlite", os.Getenv("DB_STRING"))
+	db, migrator := openDatabase(appConfig)
+	prepareDatabase(context.Background(), appConfig, migrator)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), appConfig.Tracing, version)
 	if err != nil {
-		log.Fatalf("couldnt open database: %v", err)
+		log.Fatalf("couldnt set up tracing: %v", err)
 	}
 
-	cfg := api.ApiConfig{Port: ":" + os.Getenv("PORT"), DB: database.New(db)}
+	appMetrics := metrics.New()
+
+	cfg := api.ApiConfig{
+		Config:      appConfig,
+		DB:          database.New(tracing.WrapDB(appMetrics.WrapDB(db))),
+		Conn:        db,
+		Mailer:      loadMailer(appConfig.Mail),
+		RateLimiter: ratelimit.NewMemoryStore(),
+		Metrics:     appMetrics,
+	}
+
+	appMetrics.TrackActiveSessions(func(ctx context.Context) (int64, error) {
+		return cfg.DB.CountActiveSessions(ctx, time.Now())
+	})
+
+	// The global limit applies to every request by ip, anonymous auth routes get a stricter one
+	// and logged in routes are limited per user.
+	globalLimit := cfg.RateLimitByIP("global", appConfig.RateLimits.Global)
+	authLimit := cfg.RateLimitByIP("auth", appConfig.RateLimits.Auth)
+	apiLimit := cfg.RateLimitByUser("api", appConfig.RateLimits.API)
+	searchLimit := cfg.RateLimitByUser("search", appConfig.RateLimits.Search)
+
+	worker := reminders.NewWorker(cfg.DB, loadNotifiers(appConfig.Reminders, cfg.Mailer))
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		worker.Run(workerCtx)
+	}()
+
+	cfg.Health = &api.Health{
+		DB:        db,
+		Migrator:  migrator,
+		Reminders: worker,
+		Version:   version,
+		Commit:    buildCommit(),
+		StartedAt: time.Now(),
+	}
 
 	e := echo.New()
+	e.IPExtractor = api.IPExtractor(appConfig.Server.TrustedProxies)
 	e.Use(middleware.Logger())
+	e.Use(tracing.Middleware())
+	e.Use(appMetrics.Middleware())
+	e.Use(globalLimit)
 
-	e.GET("/health", func(c echo.Context) error {
-		return c.String(http.StatusOK, "ping")
-	})
+	e.GET("/healthz", cfg.HandleLiveness)
+	// /health predates /healthz, probes that still use it keep working
+	e.GET("/health", cfg.HandleLiveness)
+	e.GET("/readyz", cfg.HandleReadiness)
+	e.GET("/metrics", echo.WrapHandler(appMetrics.Handler()))
+
+	e.POST("/api/signup", cfg.HandleCreateUser, authLimit)
+	e.POST("/api/login", cfg.HandleLoginUser, authLimit)
+	e.POST("/api/login/2fa", cfg.HandleLoginTwoFactor, authLimit)
+	e.POST("/api/token/refresh", cfg.HandleRefreshToken, authLimit)
+	e.POST("/api/password/forgot", cfg.HandleForgotPassword, authLimit)
+	e.POST("/api/password/reset", cfg.HandleResetPassword, authLimit)
+	e.GET("/api/verify-email", cfg.HandleVerifyEmail, authLimit)
+	e.POST("/api/verify-email/resend", cfg.HandleResendVerification, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.POST("/api/logout", cfg.HandleLogoutUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.POST("/api/logout/all", cfg.HandleLogoutAll, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.GET("/api/sessions", cfg.HandleGetSessions, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.DELETE("/api/sessions/:id", cfg.HandleRevokeSession, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.GET("/api/me", cfg.HandleGetMe, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeUserRead))
+	e.POST("/api/2fa/setup", cfg.HandleSetupTwoFactor, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.POST("/api/2fa/confirm", cfg.HandleConfirmTwoFactor, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.POST("/api/2fa/disable", cfg.HandleDisableTwoFactor, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.POST("/api/2fa/recovery-codes", cfg.HandleRegenerateRecoveryCodes, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.PUT("/api/users", cfg.HandleUpdateUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.POST("/api/keys", cfg.HandleCreateApiKey, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.GET("/api/keys", cfg.HandleGetApiKeys, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+	e.DELETE("/api/keys/:id", cfg.HandleRevokeApiKey, cfg.LoggedInMiddleware, apiLimit, api.RequireSession)
+
+	e.POST("/api/tasks", cfg.HandleCreateTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.GET("/api/tasks", cfg.HandleGetAllUsersTasks, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksRead))
+	e.GET("/api/tasks/:id", cfg.HandleGetTaskByID, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksRead))
+	e.PUT("/api/tasks/:id", cfg.HandleUpdateTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.DELETE("/api/tasks/:id", cfg.HandleDeleteTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.POST("/api/tasks/:id/complete", cfg.HandleCompleteTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.POST("/api/tasks/:id/reopen", cfg.HandleReopenTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.POST("/api/tasks/:id/subtasks", cfg.HandleCreateSubtask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.GET("/api/tasks/:id/tree", cfg.HandleGetTaskTree, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksRead))
+	e.PUT("/api/tasks/:id/parent", cfg.HandleMoveTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.POST("/api/tasks/:id/tags", cfg.HandleAttachTag, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.DELETE("/api/tasks/:id/tags/:tagID", cfg.HandleDetachTag, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.POST("/api/tasks/:id/shares", cfg.HandleShareTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.GET("/api/tasks/:id/shares", cfg.HandleGetTaskShares, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksRead))
+	e.DELETE("/api/tasks/:id/shares/:userID", cfg.HandleUnshareTask, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.DELETE("/api/tasks/:id/recurrence", cfg.HandleStopRecurrence, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.POST("/api/tasks/:id/reminders", cfg.HandleCreateReminder, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.GET("/api/tasks/:id/reminders", cfg.HandleGetTaskReminders, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksRead))
+	e.DELETE("/api/tasks/:id/reminders/:reminderID", cfg.HandleDeleteReminder, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksWrite))
+	e.GET("/api/tasks/search", cfg.HandleSearchTasks, cfg.LoggedInMiddleware, searchLimit, api.RequireScope(api.ScopeTasksRead))
 
-	e.POST("/api/signup", cfg.HandleCreateUser)
-	e.POST("/api/login", cfg.HandleLoginUser)
-	e.POST("/api/logout", cfg.HandleLogoutUser, cfg.LoggedInMiddleware)
-	e.GET("/api/me", cfg.HandleGetMe, cfg.LoggedInMiddleware)
-	e.PUT("/api/users", cfg.HandleUpdateUser, cfg.LoggedInMiddleware)
+	e.POST("/api/tags", cfg.HandleCreateTag, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTagsWrite))
+	e.GET("/api/tags", cfg.HandleGetUsersTags, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTagsRead))
+	e.PUT("/api/tags/:id", cfg.HandleUpdateTag, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTagsWrite))
+	e.DELETE("/api/tags/:id", cfg.HandleDeleteTag, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTagsWrite))
 
-	e.POST("/api/tasks", cfg.HandleCreateTask, cfg.LoggedInMiddleware)
-	e.GET("/api/tasks", cfg.HandleGetAllUsersTasks, cfg.LoggedInMiddleware)
-	e.GET("/api/tasks/:id", cfg.HandleGetTaskByID, cfg.LoggedInMiddleware)
-	e.PUT("/api/tasks/:id", cfg.HandleUpdateTask, cfg.LoggedInMiddleware)
-	e.DELETE("/api/tasks/:id", cfg.HandleDeleteTask, cfg.LoggedInMiddleware)
-	e.GET("/api/tasks/search", cfg.HandleGetTasksWhereTitleOrDescriptionLike, cfg.LoggedInMiddleware)
+	e.GET("/api/recurrence/preview", cfg.HandlePreviewRecurrence, cfg.LoggedInMiddleware, apiLimit, api.RequireScope(api.ScopeTasksRead))
 
-	e.Logger.Fatal(e.Start(cfg.Port))
+	e.GET("/api/admin/roles", cfg.HandleGetRoles, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersRead))
+	e.GET("/api/admin/users", cfg.HandleAdminGetUsers, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersRead))
+	e.GET("/api/admin/users/:id", cfg.HandleAdminGetUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersRead))
+	e.DELETE("/api/admin/users/:id", cfg.HandleAdminDeleteUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersDelete))
+	e.POST("/api/admin/users/:id/roles", cfg.HandleAssignRole, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionRolesAssign))
+	e.DELETE("/api/admin/users/:id/roles/:role", cfg.HandleRemoveRole, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionRolesAssign))
+	e.POST("/api/admin/users/:id/disable", cfg.HandleAdminDisableUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersDisable))
+	e.POST("/api/admin/users/:id/enable", cfg.HandleAdminEnableUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersDisable))
+	e.POST("/api/admin/users/:id/unlock", cfg.HandleAdminUnlockUser, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionUsersUnlock))
+	e.DELETE("/api/admin/users/:id/sessions", cfg.HandleAdminRevokeUserSessions, cfg.LoggedInMiddleware, apiLimit, api.RequireSession, cfg.RequirePermission(api.PermissionSessionsRevoke))
+
+	serveErr := serve(e, appConfig, cfg.Health)
+	if serveErr != nil {
+		log.Printf("server stopped: %v", serveErr)
+	}
+
+	stopWorker()
+	<-workerDone
+
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), appConfig.Server.ShutdownTimeout)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Printf("couldnt flush traces: %v", err)
+	}
+	cancelTracing()
+
+	if err := db.Close(); err != nil {
+		log.Printf("couldnt close database: %v", err)
+	}
+
+	if serveErr != nil {
+		os.Exit(1)
+	}
+	log.Print("server stopped")
 }
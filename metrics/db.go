@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+)
+
+// unknownQuery labels queries that don't start with the comment sqlc names them with.
+const unknownQuery = "unknown"
+
+// WrapDB times every query run through db by the name sqlc gave it, use it as
+// database.New(m.WrapDB(db)).
+func (m *Metrics) WrapDB(db database.DBTX) database.DBTX {
+	if m == nil {
+		return db
+	}
+
+	return &instrumentedDB{db: db, metrics: m}
+}
+
+type instrumentedDB struct {
+	db      database.DBTX
+	metrics *Metrics
+}
+
+func (d *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer d.observe(query, time.Now())
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+func (d *instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	defer d.observe(query, time.Now())
+	return d.db.PrepareContext(ctx, query)
+}
+
+func (d *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer d.observe(query, time.Now())
+	return d.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext only measures the query until its first row is ready, scanning it is left to
+// the caller.
+func (d *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer d.observe(query, time.Now())
+	return d.db.QueryRowContext(ctx, query, args...)
+}
+
+func (d *instrumentedDB) observe(query string, start time.Time) {
+	d.metrics.queryDuration.WithLabelValues(QueryName(query)).Observe(time.Since(start).Seconds())
+}
+
+// QueryName returns the name of a query generated by sqlc, taken from the
+// "-- name: GetUserByID :one" comment it starts with.
+func QueryName(query string) string {
+	line, _, _ := strings.Cut(query, "\n")
+
+	name, ok := strings.CutPrefix(strings.TrimSpace(line), "-- name:")
+	if !ok {
+		return unknownQuery
+	}
+
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return unknownQuery
+	}
+
+	return fields[0]
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that didn't match any route, their paths would give every
+// scanner probing the server a series of its own.
+const unmatchedRoute = "unmatched"
+
+// Middleware counts and times every request by method, route and status. The route is the
+// pattern it matched, /api/tasks/:id rather than the path of the request.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m == nil {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				} else if !c.Response().Committed {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			route := c.Path()
+			if route == "" || status == http.StatusNotFound && route == "/*" {
+				route = unmatchedRoute
+			}
+
+			labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+			m.requests.WithLabelValues(labels...).Inc()
+			m.requestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
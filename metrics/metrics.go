@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "taskin"
+
+// Results of a login attempt counted by RecordLogin.
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	LoginBlocked = "blocked"
+)
+
+// activeSessionsTimeout bounds the query counting the active sessions on every scrape.
+const activeSessionsTimeout = 5 * time.Second
+
+// Metrics holds the collectors of the server in a registry of its own, so tests can create as
+// many as they like. Its methods do nothing on a nil *Metrics.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	logins          *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of http requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of http requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Latency of database queries by sqlc query name.",
+			Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"query"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Number of login attempts by result.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.queryDuration,
+		m.logins,
+	)
+
+	for _, result := range []string{LoginSuccess, LoginFailure, LoginBlocked} {
+		m.logins.WithLabelValues(result)
+	}
+
+	return m
+}
+
+// Registry is where the collectors are registered, more can be added to it.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// TrackActiveSessions exposes the number of active sessions, count is called on every scrape.
+func (m *Metrics) TrackActiveSessions(count func(ctx context.Context) (int64, error)) {
+	if m == nil {
+		return
+	}
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Number of sessions that are neither revoked nor expired.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), activeSessionsTimeout)
+		defer cancel()
+
+		n, err := count(ctx)
+		if err != nil {
+			log.Printf("couldnt count active sessions: %v", err)
+			return 0
+		}
+
+		return float64(n)
+	}))
+}
+
+// RecordLogin counts a login attempt with one of the Login* results.
+func (m *Metrics) RecordLogin(result string) {
+	if m == nil {
+		return
+	}
+
+	m.logins.WithLabelValues(result).Inc()
+}
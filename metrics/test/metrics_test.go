@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/metrics"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+
+	return string(body)
+}
+
+func TestQueryName(t *testing.T) {
+	assert.Equal(t, "GetUserByID", metrics.QueryName("-- name: GetUserByID :one\nSELECT * FROM users WHERE id = ?"))
+	assert.Equal(t, "CountActiveSessions", metrics.QueryName("-- name: CountActiveSessions :one"))
+	assert.Equal(t, "unknown", metrics.QueryName("SELECT 1"))
+	assert.Equal(t, "unknown", metrics.QueryName("-- name:\nSELECT 1"))
+}
+
+func TestMiddleware(t *testing.T) {
+	m := metrics.New()
+
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/api/tasks/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "task")
+	})
+	e.GET("/api/broken", func(c echo.Context) error {
+		return errors.New("broken")
+	})
+
+	for _, path := range []string{"/api/tasks/1", "/api/tasks/2", "/api/broken", "/wp-login.php"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `taskin_http_requests_total{method="GET",route="/api/tasks/:id",status="200"} 2`)
+	assert.Contains(t, body, `taskin_http_requests_total{method="GET",route="/api/broken",status="500"} 1`)
+	assert.Contains(t, body, `taskin_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `taskin_http_request_duration_seconds_count{method="GET",route="/api/tasks/:id",status="200"} 2`)
+	assert.NotContains(t, body, "/api/tasks/1")
+	assert.NotContains(t, body, "wp-login")
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestWrapDB(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE sessions(id TEXT, revoked_at TIMESTAMP, expires_at TIMESTAMP NOT NULL)`)
+	assert.NoError(t, err)
+
+	m := metrics.New()
+	queries := database.New(m.WrapDB(db))
+
+	count, err := queries.CountActiveSessions(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `taskin_db_query_duration_seconds_count{query="CountActiveSessions"} 1`)
+}
+
+func TestLoginsAndActiveSessions(t *testing.T) {
+	m := metrics.New()
+	m.TrackActiveSessions(func(ctx context.Context) (int64, error) {
+		return 3, nil
+	})
+
+	m.RecordLogin(metrics.LoginSuccess)
+	m.RecordLogin(metrics.LoginFailure)
+	m.RecordLogin(metrics.LoginFailure)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `taskin_logins_total{result="success"} 1`)
+	assert.Contains(t, body, `taskin_logins_total{result="failure"} 2`)
+	assert.Contains(t, body, `taskin_logins_total{result="blocked"} 0`)
+	assert.Contains(t, body, "taskin_active_sessions 3")
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *metrics.Metrics
+
+	assert.NotPanics(t, func() {
+		m.RecordLogin(metrics.LoginSuccess)
+		m.TrackActiveSessions(nil)
+	})
+
+	db := &sql.DB{}
+	assert.Same(t, db, m.WrapDB(db))
+}
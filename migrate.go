@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/sql/schema"
+)
+
+const migrateUsage = "usage: taskin migrate <up|down|status> [flags]"
+
+func loadConfig(args []string) config.Config {
+	appConfig, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	return appConfig
+}
+
+func openDatabase(appConfig config.Config) (*sql.DB, *schema.Migrator) {
+	db, err := sql.Open("sqlite", appConfig.DSN())
+	if err != nil {
+		log.Fatalf("couldnt open database: %v", err)
+	}
+
+	migrator, err := schema.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("couldnt load migrations: %v", err)
+	}
+
+	return db, migrator
+}
+
+// prepareDatabase makes sure the schema matches the binary before the server starts, either by
+// migrating it or, with AUTO_MIGRATE off, by refusing to start until it's migrated by hand.
+func prepareDatabase(ctx context.Context, appConfig config.Config, migrator *schema.Migrator) {
+	if appConfig.AutoMigrate {
+		results, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("couldnt migrate database: %v", err)
+		}
+		for _, result := range results {
+			log.Printf("applied migration %s", result)
+		}
+		return
+	}
+
+	if err := migrator.Check(ctx); err != nil {
+		log.Fatalf("couldnt check database version: %v", err)
+	}
+
+	pending, err := migrator.HasPending(ctx)
+	if err != nil {
+		log.Fatalf("couldnt check pending migrations: %v", err)
+	}
+	if pending {
+		log.Fatal("database has pending migrations, apply them with `taskin migrate up`")
+	}
+}
+
+// runMigrate is the migrate subcommand, it takes the same flags as the server.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		return fmt.Errorf("unknown migrate command %q, %s", command, migrateUsage)
+	}
+
+	db, migrator := openDatabase(loadConfig(args[1:]))
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if len(results) == 0 {
+			fmt.Println("database is up to date")
+		}
+
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result)
+
+	case "status":
+		if err := migrator.Check(ctx); err != nil {
+			return err
+		}
+
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-28s %s\n", status.Source.Path, appliedAt)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/mail"
+)
+
+// EmailNotifier sends reminders to the task owner's email address.
+type EmailNotifier struct {
+	Mailer mail.Mailer
+}
+
+func NewSMTPNotifier(addr, from, username, password string) (*EmailNotifier, error) {
+	mailer, err := mail.NewSMTPMailer(addr, from, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailNotifier{Mailer: mailer}, nil
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	return n.Mailer.Send(ctx, mail.Message{
+		To:      reminder.Email,
+		Subject: "Reminder: " + reminder.TaskTitle,
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nyour task \"%s\" is due %s.\n\n%s",
+			reminder.Username, reminder.TaskTitle, reminder.DueUntil.Format(time.RFC1123), reminder.TaskDescription,
+		),
+	})
+}
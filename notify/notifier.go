@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Reminder is everything a notifier needs to tell a user that one of their tasks is due soon.
+type Reminder struct {
+	ID              string
+	TaskID          string
+	TaskTitle       string
+	TaskDescription string
+	DueUntil        time.Time
+	RemindAt        time.Time
+	UserID          string
+	Username        string
+	Email           string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, reminder Reminder) error
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/mail"
+	"github.com/magicznykacpur/taskin-backend/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMailer struct {
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var testReminder = notify.Reminder{
+	ID:              "reminder-1",
+	TaskID:          "task-1",
+	TaskTitle:       "pay rent",
+	TaskDescription: "transfer to landlord",
+	DueUntil:        time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+	RemindAt:        time.Date(2030, 1, 1, 11, 0, 0, 0, time.UTC),
+	UserID:          "user-1",
+	Username:        "test user",
+	Email:           "user@test.com",
+}
+
+func TestEmailNotifier(t *testing.T) {
+	mailer := &fakeMailer{}
+	notifier := notify.EmailNotifier{Mailer: mailer}
+
+	err := notifier.Notify(context.Background(), testReminder)
+	assert.NoError(t, err)
+
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "user@test.com", mailer.sent[0].To)
+	assert.Equal(t, "Reminder: pay rent", mailer.sent[0].Subject)
+	assert.Contains(t, mailer.sent[0].Body, "Hi test user")
+	assert.Contains(t, mailer.sent[0].Body, "transfer to landlord")
+}
+
+func TestNewSMTPNotifierInvalidAddr(t *testing.T) {
+	_, err := notify.NewSMTPNotifier("localhost", "taskin@test.com", "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload notify.WebhookPayload
+	var signature string
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(notify.SignatureHeader)
+		json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := notify.NewWebhookNotifier(server.URL, "webhook-secret")
+	err := notifier.Notify(context.Background(), testReminder)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "task.reminder", payload.Event)
+	assert.Equal(t, "reminder-1", payload.ReminderID)
+	assert.Equal(t, "pay rent", payload.Task.Title)
+	assert.Equal(t, "2030-01-01T12:00:00Z", payload.Task.DueUntil)
+	assert.Equal(t, "user@test.com", payload.User.Email)
+	assert.Equal(t, notify.Sign("webhook-secret", body), signature)
+}
+
+func TestWebhookNotifierErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := notify.NewWebhookNotifier(server.URL, "")
+	err := notifier.Notify(context.Background(), testReminder)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const SignatureHeader = "X-Taskin-Signature"
+
+type WebhookNotifier struct {
+	URL string
+	// Secret signs every payload with HMAC-SHA256, the hex digest is sent in SignatureHeader.
+	Secret string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Secret: secret, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type WebhookTask struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	DueUntil    string `json:"due_until"`
+}
+
+type WebhookUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type WebhookPayload struct {
+	Event      string      `json:"event"`
+	ReminderID string      `json:"reminder_id"`
+	RemindAt   string      `json:"remind_at"`
+	Task       WebhookTask `json:"task"`
+	User       WebhookUser `json:"user"`
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	body, err := json.Marshal(WebhookPayload{
+		Event:      "task.reminder",
+		ReminderID: reminder.ID,
+		RemindAt:   reminder.RemindAt.Format(time.RFC3339),
+		Task: WebhookTask{
+			ID:          reminder.TaskID,
+			Title:       reminder.TaskTitle,
+			Description: reminder.TaskDescription,
+			DueUntil:    reminder.DueUntil.Format(time.RFC3339),
+		},
+		User: WebhookUser{ID: reminder.UserID, Username: reminder.Username, Email: reminder.Email},
+	})
+	if err != nil {
+		return fmt.Errorf("couldnt marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("couldnt create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.Secret, body))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldnt deliver webhook: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that holds up to Burst requests and refills Requests of them every Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// ParseLimit parses limits written as "<requests>/<duration>" with an optional ":<burst>", e.g.
+// "120/1m" or "120/1m:30". Without a burst the whole minute worth of requests can be used at once.
+func ParseLimit(s string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(s, ":")
+
+	requests, per, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must look like <requests>/<duration>", s)
+	}
+
+	limit := Limit{}
+	var err error
+
+	limit.Requests, err = strconv.Atoi(requests)
+	if err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive number of requests", s)
+	}
+
+	limit.Per, err = time.ParseDuration(per)
+	if err != nil || limit.Per <= 0 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive duration", s)
+	}
+
+	limit.Burst = limit.Requests
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burst)
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("limit %q needs a positive burst", s)
+		}
+	}
+
+	return limit, nil
+}
+
+// Policy describes the limit in the format of the RateLimit-Policy header.
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Per.Seconds())))
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets, a shared store lets several instances of the server enforce one limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+var ErrInvalidLimit = errors.New("limit needs positive requests, per and burst")
+
+// Bucket is the state of a single token bucket.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time passed since it was last updated and takes a token
+// from it if there is one.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	rate := limit.rate()
+	burst := float64(limit.Burst)
+
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*rate)
+		b.UpdatedAt = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.Reset = seconds((burst - b.Tokens) / rate)
+
+	return result
+}
+
+// Full reports whether the bucket has refilled completely by now, a full bucket is the same as
+// one that doesn't exist.
+func (b *Bucket) Full(limit Limit, now time.Time) bool {
+	return b.Tokens+now.Sub(b.UpdatedAt).Seconds()*limit.rate() >= float64(limit.Burst)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
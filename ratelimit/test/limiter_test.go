@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("120/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 120, Per: time.Minute, Burst: 120}, limit)
+	assert.Equal(t, "120;w=60", limit.Policy())
+
+	limit, err = ratelimit.ParseLimit("10/1s:30")
+	assert.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 10, Per: time.Second, Burst: 30}, limit)
+
+	for _, invalid := range []string{"", "120", "0/1m", "120/forever", "120/-1m", "120/1m:0", "120/1m:lots"} {
+		_, err := ratelimit.ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBucketTake(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Per: time.Second, Burst: 3}
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	bucket := ratelimit.NewBucket(limit, now)
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result := bucket.Take(limit, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result := bucket.Take(limit, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// half a token isn't enough
+	result = bucket.Take(limit, now.Add(500*time.Millisecond))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	result = bucket.Take(limit, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// the bucket never holds more than the burst
+	assert.True(t, bucket.Full(limit, now.Add(time.Hour)))
+	result = bucket.Take(limit, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 1, Per: time.Hour, Burst: 2}
+
+	for range 2 {
+		result, err := store.Take(context.Background(), "first", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := store.Take(context.Background(), "first", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// every key has its own bucket
+	result, err = store.Take(context.Background(), "second", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	_, err = store.Take(context.Background(), "first", ratelimit.Limit{})
+	assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+}
@@ -0,0 +1,219 @@
+package reminders
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/notify"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Worker periodically claims due reminders and dispatches them through the notifier registered
+// for their channel. Claiming a reminder and recording its delivery are persisted, so a restart
+// never sends a reminder that was already marked as sent.
+type Worker struct {
+	DB        *database.Queries
+	Notifiers map[string]notify.Notifier
+
+	Interval    time.Duration
+	BatchSize   int64
+	MaxAttempts int64
+	SendTimeout time.Duration
+	// ClaimTimeout is how long a reminder can stay claimed before it is considered abandoned,
+	// e.g. because the server was killed mid-delivery, and is queued again.
+	ClaimTimeout time.Duration
+
+	mu     sync.Mutex
+	health Health
+}
+
+// Health is what the worker last did, it's reported by the health endpoints.
+type Health struct {
+	Running   bool
+	LastRunAt time.Time
+	LastError string
+}
+
+func (w *Worker) Health() Health {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.health
+}
+
+func (w *Worker) recordRun(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.health.LastRunAt = time.Now().UTC()
+	w.health.LastError = ""
+	if err != nil {
+		w.health.LastError = err.Error()
+	}
+}
+
+func (w *Worker) setRunning(running bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.health.Running = running
+}
+
+func NewWorker(db *database.Queries, notifiers map[string]notify.Notifier) *Worker {
+	return &Worker{
+		DB:           db,
+		Notifiers:    notifiers,
+		Interval:     30 * time.Second,
+		BatchSize:    50,
+		MaxAttempts:  5,
+		SendTimeout:  30 * time.Second,
+		ClaimTimeout: 10 * time.Minute,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	w.setRunning(true)
+	defer w.setRunning(false)
+
+	for {
+		_, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("reminders: %v", err)
+		}
+		w.recordRun(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce delivers every reminder that is due right now and returns how many were sent.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+
+	_, err := w.DB.ReleaseStaleReminderClaims(
+		ctx,
+		database.ReleaseStaleReminderClaimsParams{
+			Now:           now,
+			ClaimedBefore: sqlNullTime(now.Add(-w.ClaimTimeout)),
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("couldnt release stale claims: %v", err)
+	}
+
+	claimed, err := w.DB.ClaimDueReminders(ctx, database.ClaimDueRemindersParams{Now: now, BatchSize: w.BatchSize})
+	if err != nil {
+		return 0, fmt.Errorf("couldnt claim due reminders: %v", err)
+	}
+	if len(claimed) == 0 {
+		return 0, nil
+	}
+
+	attempts := map[string]int64{}
+	ids := []string{}
+	for _, reminder := range claimed {
+		attempts[reminder.ID] = reminder.Attempts
+		ids = append(ids, reminder.ID)
+	}
+
+	deliveries, err := w.DB.GetReminderDeliveries(ctx, ids)
+	if err != nil {
+		return 0, fmt.Errorf("couldnt retrieve reminder deliveries: %v", err)
+	}
+
+	sent := 0
+	for _, delivery := range deliveries {
+		attempt := attempts[delivery.ID]
+		delete(attempts, delivery.ID)
+
+		if err := w.deliver(ctx, delivery); err != nil {
+			log.Printf("reminders: couldnt deliver reminder %s: %v", delivery.ID, err)
+			w.markFailed(ctx, delivery.ID, attempt, err)
+			continue
+		}
+
+		err := w.DB.MarkReminderSent(
+			ctx,
+			database.MarkReminderSentParams{
+				SentAt:    sqlNullTime(time.Now().UTC()),
+				UpdatedAt: time.Now().UTC(),
+				ID:        delivery.ID,
+			},
+		)
+		if err != nil {
+			log.Printf("reminders: couldnt mark reminder %s as sent: %v", delivery.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	for id, attempt := range attempts {
+		w.markFailed(ctx, id, attempt, fmt.Errorf("task or user no longer exists, or the task isnt shared with the user anymore"))
+	}
+
+	return sent, nil
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery database.GetReminderDeliveriesRow) error {
+	notifier, ok := w.Notifiers[delivery.Channel]
+	if !ok {
+		return fmt.Errorf("no notifier configured for channel %s", delivery.Channel)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.SendTimeout)
+	defer cancel()
+
+	return notifier.Notify(ctx, notify.Reminder{
+		ID:              delivery.ID,
+		TaskID:          delivery.TaskID,
+		TaskTitle:       delivery.Title,
+		TaskDescription: delivery.Description,
+		DueUntil:        delivery.DueUntil,
+		RemindAt:        delivery.RemindAt,
+		UserID:          delivery.UserID,
+		Username:        delivery.Username,
+		Email:           delivery.Email,
+	})
+}
+
+func (w *Worker) markFailed(ctx context.Context, id string, attempts int64, deliveryErr error) {
+	status := StatusPending
+	if attempts+1 >= w.MaxAttempts {
+		status = StatusFailed
+	}
+
+	err := w.DB.MarkReminderFailed(
+		ctx,
+		database.MarkReminderFailedParams{
+			Status:    status,
+			LastError: deliveryErr.Error(),
+			UpdatedAt: time.Now().UTC(),
+			ID:        id,
+		},
+	)
+	if err != nil {
+		log.Printf("reminders: couldnt mark reminder %s as failed: %v", id, err)
+	}
+}
+
+func sqlNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
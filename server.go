@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/api"
+	"github.com/magicznykacpur/taskin-backend/config"
+)
+
+// serve runs the server until it gets SIGINT or SIGTERM. Then readiness starts failing, after
+// the drain delay it stops accepting connections and waits up to the shutdown timeout for
+// in-flight requests to finish.
+func serve(e *echo.Echo, appConfig config.Config, health *api.Health) error {
+	e.Server.ReadTimeout = appConfig.Server.ReadTimeout
+	e.Server.WriteTimeout = appConfig.Server.WriteTimeout
+	e.Server.IdleTimeout = appConfig.Server.IdleTimeout
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- e.Start(appConfig.Addr())
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// a second signal kills the process right away
+	stop()
+
+	health.StartDraining()
+	if appConfig.Server.DrainDelay > 0 {
+		log.Printf("draining, accepting connections for another %s", appConfig.Server.DrainDelay)
+		time.Sleep(appConfig.Server.DrainDelay)
+	}
+
+	log.Printf("shutting down, waiting up to %s for in-flight requests", appConfig.Server.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appConfig.Server.ShutdownTimeout)
+	defer cancel()
+
+	err := e.Shutdown(shutdownCtx)
+	if startErr := <-errs; startErr != nil && !errors.Is(startErr, http.ErrServerClosed) {
+		return startErr
+	}
+
+	return err
+}
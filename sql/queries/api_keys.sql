@@ -0,0 +1,20 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys(id, user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetApiKeyByHash :one
+SELECT * FROM api_keys WHERE key_hash = ?;
+
+-- name: GetUserApiKeys :many
+SELECT * FROM api_keys
+WHERE user_id = ? AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: TouchApiKey :exec
+UPDATE api_keys SET last_used_at = ? WHERE id = ?;
+
+-- name: RevokeApiKey :execrows
+UPDATE api_keys
+SET revoked_at = ?
+WHERE id = ? AND user_id = ? AND revoked_at IS NULL;
@@ -0,0 +1,26 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens(id, user_id, email, token_hash, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetEmailVerificationTokenByHash :one
+SELECT * FROM email_verification_tokens WHERE token_hash = ?;
+
+-- name: UseEmailVerificationToken :execrows
+UPDATE email_verification_tokens
+SET used_at = sqlc.arg(used_at)
+WHERE id = sqlc.arg(id) AND used_at IS NULL AND expires_at > sqlc.arg(used_at);
+
+-- name: InvalidateEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = ?
+WHERE user_id = ? AND used_at IS NULL;
+
+-- name: CountEmailVerificationTokensSince :one
+SELECT COUNT(*) FROM email_verification_tokens
+WHERE user_id = ? AND created_at > ?;
+
+-- name: GetLatestEmailVerificationToken :one
+SELECT * FROM email_verification_tokens
+WHERE user_id = ?
+ORDER BY created_at DESC
+LIMIT 1;
@@ -0,0 +1,18 @@
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts WHERE scope = ? AND subject = ?;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts(scope, subject, failures, last_failed_at)
+VALUES (?, ?, 1, ?)
+ON CONFLICT(scope, subject) DO UPDATE SET
+    failures = login_attempts.failures + 1,
+    last_failed_at = excluded.last_failed_at
+RETURNING *;
+
+-- name: BlockLoginAttempts :exec
+UPDATE login_attempts
+SET blocked_until = ?, locked_at = ?
+WHERE scope = ? AND subject = ?;
+
+-- name: ClearLoginAttempts :exec
+DELETE FROM login_attempts WHERE scope = ? AND subject = ?;
@@ -0,0 +1,16 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens(id, user_id, token_hash, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetPasswordResetTokenByHash :one
+SELECT * FROM password_reset_tokens WHERE token_hash = ?;
+
+-- name: UsePasswordResetToken :execrows
+UPDATE password_reset_tokens
+SET used_at = sqlc.arg(used_at)
+WHERE id = sqlc.arg(id) AND used_at IS NULL AND expires_at > sqlc.arg(used_at);
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = ?
+WHERE user_id = ? AND used_at IS NULL;
@@ -1,12 +1,20 @@
 -- name: CreateRefreshToken :exec
-INSERT INTO refresh_tokens(user_id, token, created_at, updated_at, is_revoked, expires_at)
-VALUES (?, ?, ?, ?, 0, ?);
+INSERT INTO refresh_tokens(id, user_id, family_id, token_hash, created_at, updated_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?);
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens SET is_revoked = 1 WHERE user_id = ?;
 
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET is_revoked = 1, updated_at = ? WHERE family_id = ?;
+
 -- name: GetValidRefreshTokenForUserId :one
-SELECT * FROM refresh_tokens WHERE is_revoked = 0 AND expires_at > ? AND user_id = ?;
+SELECT * FROM refresh_tokens WHERE is_revoked = 0 AND consumed_at IS NULL AND expires_at > ? AND user_id = ?;
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens WHERE token_hash = ?;
 
--- name: GetValidRefreshTokenByValue :one
-SELECT * FROM refresh_tokens WHERE is_revoked = 0 AND token = ? AND expires_at > ?;
\ No newline at end of file
+-- name: ConsumeRefreshToken :execrows
+UPDATE refresh_tokens
+SET consumed_at = ?, updated_at = ?
+WHERE id = ? AND consumed_at IS NULL AND is_revoked = 0;
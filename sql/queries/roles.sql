@@ -0,0 +1,37 @@
+-- name: GetRoles :many
+SELECT * FROM roles ORDER BY name;
+
+-- name: GetRoleByName :one
+SELECT * FROM roles WHERE name = ?;
+
+-- name: GetRolePermissions :many
+SELECT * FROM role_permissions ORDER BY role, permission;
+
+-- name: GetUserRoles :many
+SELECT * FROM user_roles WHERE user_id = ? ORDER BY role;
+
+-- name: GetRolesForUsers :many
+SELECT * FROM user_roles WHERE user_id IN (sqlc.slice(user_ids)) ORDER BY user_id, role;
+
+-- name: AssignUserRole :exec
+INSERT INTO user_roles(user_id, role, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RemoveUserRole :execrows
+-- The last active admin can't lose the admin role, no row is deleted then.
+DELETE FROM user_roles
+WHERE user_roles.user_id = sqlc.arg(user_id) AND user_roles.role = sqlc.arg(role)
+AND (
+    user_roles.role != 'admin'
+    OR (
+        SELECT COUNT(*) FROM user_roles AS admin_roles
+        JOIN users AS admins ON admins.id = admin_roles.user_id
+        WHERE admin_roles.role = 'admin' AND admins.disabled_at IS NULL AND admins.id != sqlc.arg(user_id)
+    ) > 0
+);
+
+-- name: CountUserPermission :one
+SELECT COUNT(*) FROM user_roles
+JOIN role_permissions ON role_permissions.role = user_roles.role
+WHERE user_roles.user_id = ? AND role_permissions.permission = ?;
@@ -0,0 +1,34 @@
+-- name: CreateSession :one
+INSERT INTO sessions(id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetSessionByID :one
+SELECT * FROM sessions WHERE id = ?;
+
+-- name: GetActiveUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+ORDER BY last_used_at DESC;
+
+-- name: RefreshSession :exec
+UPDATE sessions
+SET last_used_at = ?, expires_at = ?, user_agent = ?, ip_address = ?
+WHERE id = ?;
+
+-- name: TouchSession :exec
+UPDATE sessions SET last_used_at = ? WHERE id = ?;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = ?
+WHERE id = ? AND user_id = ? AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = ?
+WHERE user_id = ? AND revoked_at IS NULL;
+
+-- name: CountActiveSessions :one
+SELECT COUNT(*) FROM sessions
+WHERE revoked_at IS NULL AND expires_at > ?;
@@ -0,0 +1,34 @@
+-- name: CreateTag :one
+INSERT INTO tags(id, created_at, updated_at, name, color, user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetUsersTags :many
+SELECT * FROM tags WHERE user_id = ? ORDER BY name;
+
+-- name: GetTagByID :one
+SELECT * FROM tags WHERE id = ? AND user_id = ?;
+
+-- name: UpdateTagByID :one
+UPDATE tags
+SET name = ?, color = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING *;
+
+-- name: DeleteTagByID :exec
+DELETE FROM tags WHERE id = ? AND user_id = ?;
+
+-- name: AttachTagToTask :exec
+INSERT INTO task_tags(task_id, tag_id)
+VALUES (?, ?)
+ON CONFLICT (task_id, tag_id) DO NOTHING;
+
+-- name: DetachTagFromTask :exec
+DELETE FROM task_tags WHERE task_id = ? AND tag_id = ?;
+
+-- name: GetTagsForTasks :many
+SELECT task_tags.task_id, sqlc.embed(tags)
+FROM task_tags
+JOIN tags ON tags.id = task_tags.tag_id
+WHERE task_tags.task_id IN (sqlc.slice(task_ids))
+ORDER BY tags.name;
@@ -0,0 +1,63 @@
+-- name: CreateTaskReminder :one
+INSERT INTO task_reminders(id, created_at, updated_at, task_id, user_id, minutes_before, channel, remind_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetTaskReminders :many
+SELECT * FROM task_reminders
+WHERE task_id = ? AND user_id = ?
+ORDER BY remind_at;
+
+-- name: GetAllTaskReminders :many
+SELECT * FROM task_reminders WHERE task_id = ?;
+
+-- name: DeleteTaskReminder :exec
+DELETE FROM task_reminders WHERE id = ? AND task_id = ? AND user_id = ?;
+
+-- name: DeleteUsersTaskReminders :exec
+DELETE FROM task_reminders WHERE task_id = ? AND user_id = ?;
+
+-- name: RescheduleTaskReminder :exec
+UPDATE task_reminders
+SET remind_at = ?, status = 'pending', attempts = 0, last_error = '', claimed_at = NULL, sent_at = NULL, updated_at = ?
+WHERE id = ?;
+
+-- name: ClaimDueReminders :many
+UPDATE task_reminders
+SET status = 'sending', updated_at = sqlc.arg(now), claimed_at = sqlc.arg(now)
+WHERE task_reminders.id IN (
+    SELECT due.id FROM task_reminders AS due
+    JOIN tasks ON tasks.id = due.task_id
+    WHERE due.status = 'pending'
+    AND due.remind_at <= sqlc.arg(now)
+    AND tasks.status NOT IN ('done', 'cancelled')
+    AND (tasks.user_id = due.user_id OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = due.user_id))
+    ORDER BY due.remind_at
+    LIMIT sqlc.arg(batch_size)
+)
+RETURNING *;
+
+-- name: GetReminderDeliveries :many
+SELECT task_reminders.id, task_reminders.channel, task_reminders.remind_at,
+    tasks.id AS task_id, tasks.title, tasks.description, tasks.due_until,
+    users.id AS user_id, users.username, users.email
+FROM task_reminders
+JOIN tasks ON tasks.id = task_reminders.task_id
+JOIN users ON users.id = task_reminders.user_id
+WHERE task_reminders.id IN (sqlc.slice(ids))
+AND (tasks.user_id = users.id OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = users.id));
+
+-- name: MarkReminderSent :exec
+UPDATE task_reminders
+SET status = 'sent', sent_at = ?, updated_at = ?
+WHERE id = ?;
+
+-- name: MarkReminderFailed :exec
+UPDATE task_reminders
+SET status = sqlc.arg(status), attempts = attempts + 1, last_error = sqlc.arg(last_error), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ReleaseStaleReminderClaims :execrows
+UPDATE task_reminders
+SET status = 'pending', updated_at = sqlc.arg(now), claimed_at = NULL
+WHERE status = 'sending' AND claimed_at < sqlc.arg(claimed_before);
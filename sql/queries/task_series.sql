@@ -0,0 +1,30 @@
+-- name: CreateTaskSeries :one
+INSERT INTO task_series(id, created_at, updated_at, rule, starts_at, title, description, priority, user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetTaskSeriesByID :one
+SELECT * FROM task_series WHERE id = ?;
+
+-- name: GetTaskSeriesByIDs :many
+SELECT * FROM task_series WHERE id IN (sqlc.slice(ids));
+
+-- name: UpdateTaskSeriesByID :one
+UPDATE task_series
+SET rule = ?, starts_at = ?, title = ?, description = ?, priority = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING *;
+
+-- name: GetSeriesOccurrence :one
+SELECT * FROM tasks WHERE series_id = ? AND due_until = ?;
+
+-- name: SetTaskSeries :one
+UPDATE tasks
+SET series_id = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
+RETURNING *;
+
+-- name: CopyTaskTags :exec
+INSERT INTO task_tags(task_id, tag_id)
+SELECT sqlc.arg(new_task_id), task_tags.tag_id FROM task_tags
+WHERE task_tags.task_id = sqlc.arg(task_id);
@@ -1,28 +1,81 @@
 -- name: CreateTask :one
-INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, category, user_id)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, user_id, parent_id, series_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetTaskByID :one
-SELECT * FROM tasks WHERE id = ?;
+SELECT * FROM tasks
+WHERE id = sqlc.arg(id)
+AND (tasks.user_id = sqlc.arg(user_id) OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = sqlc.arg(user_id)));
 
--- name: GetTasksByTitle :many
-SELECT * FROM tasks WHERE title LIKE ?;
+-- name: UpdateTaskByID :one
+UPDATE tasks
+SET title = ?, description = ?, priority = ?, updated_at = ?, due_until = ?, status = ?, completed_at = ?
+WHERE id = sqlc.arg(id)
+AND (tasks.user_id = sqlc.arg(user_id) OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = sqlc.arg(user_id)))
+RETURNING *;
 
--- name: GetTasksByDescription :many
-SELECT * FROM tasks WHERE description LIKE ?;
+-- name: CompleteTaskByID :one
+UPDATE tasks
+SET status = 'done', completed_at = ?, updated_at = ?
+WHERE id = sqlc.arg(id)
+AND (tasks.user_id = sqlc.arg(user_id) OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = sqlc.arg(user_id)))
+RETURNING *;
 
--- name: GetTaskByTitleAndDescription :many
-SELECT * FROM tasks WHERE title LIKE ? OR description LIKE ?;
+-- name: ReopenTaskByID :one
+UPDATE tasks
+SET status = 'todo', completed_at = NULL, updated_at = ?
+WHERE id = sqlc.arg(id)
+AND (tasks.user_id = sqlc.arg(user_id) OR tasks.id IN (SELECT task_id FROM task_shares WHERE task_shares.user_id = sqlc.arg(user_id)))
+RETURNING *;
 
--- name: GetAllUsersTasks :many
-SELECT * FROM tasks WHERE user_id = ?;
+-- name: DeleteTaskByID :exec
+DELETE FROM tasks WHERE id = ? AND user_id = ?;
 
--- name: UpdateTaskByID :one
+-- name: GetTaskTree :many
+WITH RECURSIVE tree(id) AS (
+    SELECT tasks.id FROM tasks WHERE tasks.id = sqlc.arg(id) AND tasks.user_id = sqlc.arg(user_id)
+    UNION ALL
+    SELECT tasks.id FROM tasks JOIN tree ON tasks.parent_id = tree.id
+)
+SELECT tasks.* FROM tasks
+WHERE tasks.id IN (SELECT tree.id FROM tree)
+ORDER BY tasks.created_at, tasks.id;
+
+-- name: GetTaskAncestorIDs :many
+WITH RECURSIVE ancestors(id, parent_id) AS (
+    SELECT tasks.id, tasks.parent_id FROM tasks WHERE tasks.id = ?
+    UNION ALL
+    SELECT tasks.id, tasks.parent_id FROM tasks JOIN ancestors ON tasks.id = ancestors.parent_id
+)
+SELECT ancestors.id FROM ancestors;
+
+-- name: SetTaskParent :one
 UPDATE tasks
-SET title = ?, description = ?, priority = ?, category = ?, updated_at = ?, due_until = ?
-WHERE id = ?
+SET parent_id = ?, updated_at = ?
+WHERE id = ? AND user_id = ?
 RETURNING *;
 
--- name: DeleteTaskByID :exec
-DELETE FROM tasks WHERE id = ? AND user_id = ?;
\ No newline at end of file
+-- name: ReparentSubtasks :exec
+UPDATE tasks
+SET parent_id = sqlc.arg(new_parent_id), updated_at = sqlc.arg(updated_at)
+WHERE parent_id = sqlc.arg(parent_id) AND user_id = sqlc.arg(user_id);
+
+-- name: CreateTaskShare :exec
+INSERT INTO task_shares(task_id, user_id, created_at)
+SELECT tasks.id, sqlc.arg(shared_with_id), sqlc.arg(created_at) FROM tasks
+WHERE tasks.id = sqlc.arg(task_id) AND tasks.user_id = sqlc.arg(owner_id)
+ON CONFLICT (task_id, user_id) DO NOTHING;
+
+-- name: GetTaskShares :many
+SELECT task_shares.task_id, task_shares.created_at, users.id AS user_id, users.username, users.email
+FROM task_shares
+JOIN tasks ON tasks.id = task_shares.task_id
+JOIN users ON users.id = task_shares.user_id
+WHERE task_shares.task_id = ? AND tasks.user_id = ?;
+
+-- name: DeleteTaskShare :exec
+DELETE FROM task_shares
+WHERE task_shares.task_id = sqlc.arg(task_id) AND task_shares.user_id = sqlc.arg(shared_with_id)
+AND task_shares.task_id IN (SELECT tasks.id FROM tasks WHERE tasks.user_id = sqlc.arg(owner_id));
+
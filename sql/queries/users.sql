@@ -17,8 +17,69 @@ SET email = ?, username = ?, hashed_password = ?, updated_at = ?
 WHERE id = ?
 RETURNING *;
 
--- name: AddAdminPrivilages :one
-UPDATE users SET is_admin = TRUE, updated_at = ? WHERE id = ? RETURNING *;
+-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = ?, updated_at = ? WHERE id = ?;
 
--- name: RevokeAdminPrivilages :one
-UPDATE users SET is_admin = FALSE, updated_at = ? WHERE id = ? RETURNING *;
\ No newline at end of file
+-- name: SetPendingEmail :one
+UPDATE users SET pending_email = ?, updated_at = ? WHERE id = ? RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email = sqlc.arg(email),
+    pending_email = CASE WHEN pending_email = sqlc.arg(email) THEN NULL ELSE pending_email END,
+    email_verified_at = sqlc.arg(email_verified_at),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetTOTPSecret :exec
+UPDATE users SET totp_secret = ?, totp_enabled_at = NULL, totp_last_step = 0, updated_at = ? WHERE id = ?;
+
+-- name: EnableTOTP :exec
+UPDATE users SET totp_enabled_at = ?, totp_last_step = ?, updated_at = ? WHERE id = ?;
+
+-- name: DisableTOTP :exec
+UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = ? WHERE id = ?;
+
+-- name: UseTOTPStep :execrows
+UPDATE users SET totp_last_step = sqlc.arg(step) WHERE id = sqlc.arg(id) AND totp_last_step < sqlc.arg(step);
+
+-- name: ListUsers :many
+SELECT users.*, (SELECT COUNT(*) FROM tasks WHERE tasks.user_id = users.id) AS task_count
+FROM users
+WHERE instr(lower(users.username), lower(sqlc.arg(query))) > 0 OR instr(lower(users.email), lower(sqlc.arg(query))) > 0
+ORDER BY users.created_at, users.id
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: GetUserTaskCounts :many
+SELECT status, COUNT(*) AS count FROM tasks WHERE user_id = ? GROUP BY status;
+
+-- name: DisableUser :one
+-- The last active admin can't be disabled, no row is returned then.
+UPDATE users SET disabled_at = sqlc.arg(disabled_at), updated_at = sqlc.arg(updated_at)
+WHERE users.id = sqlc.arg(id)
+AND (
+    NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin')
+    OR (
+        SELECT COUNT(*) FROM user_roles AS admin_roles
+        JOIN users AS admins ON admins.id = admin_roles.user_id
+        WHERE admin_roles.role = 'admin' AND admins.disabled_at IS NULL AND admins.id != sqlc.arg(id)
+    ) > 0
+)
+RETURNING *;
+
+-- name: EnableUser :one
+UPDATE users SET disabled_at = NULL, updated_at = ? WHERE id = ? RETURNING *;
+
+-- name: DeleteUser :execrows
+-- Like DisableUser this keeps the last active admin, no row is deleted then.
+DELETE FROM users
+WHERE users.id = sqlc.arg(id)
+AND (
+    NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin')
+    OR (
+        SELECT COUNT(*) FROM user_roles AS admin_roles
+        JOIN users AS admins ON admins.id = admin_roles.user_id
+        WHERE admin_roles.role = 'admin' AND admins.disabled_at IS NULL AND admins.id != sqlc.arg(id)
+    ) > 0
+);
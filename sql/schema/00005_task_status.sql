@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN status TEXT NOT NULL DEFAULT 'todo'
+    CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled'));
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN completed_at;
+ALTER TABLE tasks DROP COLUMN status;
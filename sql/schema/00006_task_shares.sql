@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE task_shares(
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (task_id, user_id)
+);
+
+-- +goose Down
+DROP TABLE task_shares;
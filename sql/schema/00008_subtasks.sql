@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN parent_id TEXT;
+CREATE INDEX tasks_parent_id_idx ON tasks(parent_id);
+
+-- +goose Down
+DROP INDEX tasks_parent_id_idx;
+ALTER TABLE tasks DROP COLUMN parent_id;
@@ -0,0 +1,54 @@
+-- +goose Up
+CREATE TABLE tags(
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL DEFAULT '',
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE task_tags(
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX task_tags_tag_id_idx ON task_tags(tag_id);
+
+INSERT INTO tags(id, created_at, updated_at, name, color, user_id)
+SELECT
+    lower(
+        hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+    ),
+    MIN(created_at),
+    MIN(created_at),
+    category,
+    '',
+    user_id
+FROM tasks
+WHERE category != ''
+GROUP BY user_id, category;
+
+INSERT INTO task_tags(task_id, tag_id)
+SELECT tasks.id, tags.id FROM tasks
+JOIN tags ON tags.user_id = tasks.user_id AND tags.name = tasks.category;
+
+ALTER TABLE tasks DROP COLUMN category;
+
+-- +goose Down
+ALTER TABLE tasks ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+UPDATE tasks SET category = COALESCE((
+    SELECT tags.name FROM task_tags
+    JOIN tags ON tags.id = task_tags.tag_id
+    WHERE task_tags.task_id = tasks.id
+    ORDER BY tags.name
+    LIMIT 1
+), '');
+
+DROP INDEX task_tags_tag_id_idx;
+DROP TABLE task_tags;
+DROP TABLE tags;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE task_series(
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    rule TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    priority INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE tasks ADD COLUMN series_id TEXT REFERENCES task_series(id) ON DELETE SET NULL;
+CREATE INDEX tasks_series_id_idx ON tasks(series_id);
+
+-- +goose Down
+DROP INDEX tasks_series_id_idx;
+ALTER TABLE tasks DROP COLUMN series_id;
+DROP TABLE task_series;
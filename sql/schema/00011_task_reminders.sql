@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE task_reminders(
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    minutes_before INTEGER NOT NULL,
+    channel TEXT NOT NULL CHECK (channel IN ('email', 'webhook')),
+    remind_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    claimed_at TIMESTAMP,
+    sent_at TIMESTAMP,
+    UNIQUE (task_id, user_id, minutes_before, channel)
+);
+
+CREATE INDEX task_reminders_status_remind_at_idx ON task_reminders(status, remind_at);
+
+-- +goose Down
+DROP INDEX task_reminders_status_remind_at_idx;
+DROP TABLE task_reminders;
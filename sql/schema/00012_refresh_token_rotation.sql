@@ -0,0 +1,33 @@
+-- +goose Up
+-- Refresh tokens used to be stored in plaintext and can't be hashed from SQL,
+-- so existing ones are dropped and users have to log in again.
+DROP TABLE refresh_tokens;
+
+CREATE TABLE refresh_tokens (
+    id TEXT NOT NULL PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP,
+    is_revoked INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens(user_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+DROP INDEX refresh_tokens_family_id_idx;
+DROP TABLE refresh_tokens;
+
+CREATE TABLE refresh_tokens (
+    user_id TEXT NOT NULL,
+    token TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    is_revoked INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
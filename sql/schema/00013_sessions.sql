@@ -0,0 +1,25 @@
+-- +goose Up
+-- A session is one logged in device, its id is the family_id of the refresh tokens issued to it.
+CREATE TABLE sessions(
+    id TEXT NOT NULL PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_name TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions(user_id);
+
+INSERT INTO sessions(id, user_id, created_at, last_used_at, expires_at)
+SELECT family_id, user_id, MIN(created_at), MAX(updated_at), MAX(expires_at)
+FROM refresh_tokens
+WHERE is_revoked = 0
+GROUP BY family_id, user_id;
+
+-- +goose Down
+DROP INDEX sessions_user_id_idx;
+DROP TABLE sessions;
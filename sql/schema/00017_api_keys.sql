@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE api_keys(
+    id TEXT NOT NULL PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- prefix is the start of the key, it lets users tell their keys apart without storing them.
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    -- scopes are space separated, e.g. "tasks:read tasks:write".
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys(user_id);
+
+-- +goose Down
+DROP INDEX api_keys_user_id_idx;
+DROP TABLE api_keys;
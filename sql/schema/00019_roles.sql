@@ -0,0 +1,64 @@
+-- +goose Up
+CREATE TABLE roles(
+    name TEXT NOT NULL PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE permissions(
+    name TEXT NOT NULL PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions(
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles(
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX user_roles_role_idx ON user_roles(role);
+
+INSERT INTO roles(name, description) VALUES
+    ('user', 'Every account, manages its own tasks'),
+    ('support', 'Helps users, can look up accounts and end their sessions'),
+    ('admin', 'Manages accounts and roles');
+
+INSERT INTO permissions(name, description) VALUES
+    ('users:read', 'List and view accounts'),
+    ('users:disable', 'Disable and enable accounts'),
+    ('users:delete', 'Delete accounts'),
+    ('sessions:revoke', 'Log accounts out everywhere'),
+    ('roles:assign', 'Assign and remove roles');
+
+INSERT INTO role_permissions(role, permission) VALUES
+    ('support', 'users:read'),
+    ('support', 'sessions:revoke'),
+    ('admin', 'users:read'),
+    ('admin', 'users:disable'),
+    ('admin', 'users:delete'),
+    ('admin', 'sessions:revoke'),
+    ('admin', 'roles:assign');
+
+INSERT INTO user_roles(user_id, role, created_at)
+SELECT id, 'user', created_at FROM users;
+
+INSERT INTO user_roles(user_id, role, created_at)
+SELECT id, 'admin', updated_at FROM users WHERE is_admin = 1;
+
+ALTER TABLE users DROP COLUMN is_admin;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN is_admin INTEGER DEFAULT FALSE NOT NULL;
+UPDATE users SET is_admin = TRUE WHERE id IN (SELECT user_id FROM user_roles WHERE role = 'admin');
+
+DROP INDEX user_roles_role_idx;
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE login_attempts(
+    scope TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP,
+    locked_at TIMESTAMP,
+    PRIMARY KEY (scope, subject)
+);
+
+INSERT INTO permissions(name, description) VALUES
+    ('users:unlock', 'Unlock accounts locked after failed logins');
+
+INSERT INTO role_permissions(role, permission) VALUES
+    ('support', 'users:unlock'),
+    ('admin', 'users:unlock');
+
+-- +goose Down
+DELETE FROM role_permissions WHERE permission = 'users:unlock';
+DELETE FROM permissions WHERE name = 'users:unlock';
+
+DROP TABLE login_attempts;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Foreign keys used to be off, so deleting a user, task or tag left the rows that should have
+-- cascaded with it behind. They can't be reached anymore and are cleaned up here.
+DELETE FROM tasks WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM task_series WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM tags WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM refresh_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM sessions WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM password_reset_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM email_verification_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM recovery_codes WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM api_keys WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM user_roles WHERE user_id NOT IN (SELECT id FROM users);
+
+DELETE FROM task_shares
+WHERE task_id NOT IN (SELECT id FROM tasks) OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM task_tags
+WHERE task_id NOT IN (SELECT id FROM tasks) OR tag_id NOT IN (SELECT id FROM tags);
+DELETE FROM task_reminders
+WHERE task_id NOT IN (SELECT id FROM tasks) OR user_id NOT IN (SELECT id FROM users);
+
+UPDATE tasks SET series_id = NULL
+WHERE series_id IS NOT NULL AND series_id NOT IN (SELECT id FROM task_series);
+
+-- +goose Down
+-- The deleted rows were unreachable, there's nothing to restore.
@@ -0,0 +1,113 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- parent_id gets a foreign key, so deleting a task deletes its subtasks with it and none can
+-- point at a missing parent. sqlite can't add one to an existing column, the table is rebuilt
+-- with foreign keys off so dropping the old one doesn't cascade into task_shares, task_tags
+-- and task_reminders. The pragma is a no-op inside a transaction, hence NO TRANSACTION.
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+UPDATE tasks SET parent_id = NULL
+WHERE parent_id IS NOT NULL AND parent_id NOT IN (SELECT id FROM tasks);
+
+CREATE TABLE tasks_new(
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    due_until TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    priority INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    completed_at TIMESTAMP,
+    parent_id TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+    series_id TEXT REFERENCES task_series(id) ON DELETE SET NULL
+);
+
+INSERT INTO tasks_new(rowid, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id)
+SELECT rowid, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+FROM tasks;
+
+DROP TABLE tasks;
+ALTER TABLE tasks_new RENAME TO tasks;
+
+CREATE INDEX tasks_parent_id_idx ON tasks(parent_id);
+CREATE INDEX tasks_series_id_idx ON tasks(series_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts(task_id, title, description) VALUES (new.id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    DELETE FROM tasks_fts WHERE task_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+    UPDATE tasks_fts SET title = new.title, description = new.description WHERE task_id = old.id;
+END;
+-- +goose StatementEnd
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE tasks_old(
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    due_until TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    priority INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    completed_at TIMESTAMP,
+    parent_id TEXT,
+    series_id TEXT REFERENCES task_series(id) ON DELETE SET NULL
+);
+
+INSERT INTO tasks_old(rowid, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id)
+SELECT rowid, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+FROM tasks;
+
+DROP TABLE tasks;
+ALTER TABLE tasks_old RENAME TO tasks;
+
+CREATE INDEX tasks_parent_id_idx ON tasks(parent_id);
+CREATE INDEX tasks_series_id_idx ON tasks(series_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts(task_id, title, description) VALUES (new.id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    DELETE FROM tasks_fts WHERE task_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+    UPDATE tasks_fts SET title = new.title, description = new.description WHERE task_id = old.id;
+END;
+-- +goose StatementEnd
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
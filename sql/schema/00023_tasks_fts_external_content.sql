@@ -0,0 +1,137 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- tasks_fts becomes an external content table over tasks, keyed on the new seq column. Looking a
+-- task up by the UNINDEXED task_id column scanned the whole index on every update and delete,
+-- the rowid is its primary key. seq is an INTEGER PRIMARY KEY, so unlike the implicit rowid of
+-- tasks it can't be renumbered by a VACUUM. tasks is rebuilt with foreign keys off for it, the
+-- same way as when parent_id got its foreign key.
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+DROP TABLE tasks_fts;
+
+CREATE TABLE tasks_new(
+    seq INTEGER PRIMARY KEY,
+    id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    due_until TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    priority INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    completed_at TIMESTAMP,
+    parent_id TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+    series_id TEXT REFERENCES task_series(id) ON DELETE SET NULL
+);
+
+INSERT INTO tasks_new(seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id)
+SELECT rowid, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+FROM tasks;
+
+DROP TABLE tasks;
+ALTER TABLE tasks_new RENAME TO tasks;
+
+CREATE INDEX tasks_parent_id_idx ON tasks(parent_id);
+CREATE INDEX tasks_series_id_idx ON tasks(series_id);
+
+CREATE VIRTUAL TABLE tasks_fts USING fts5(
+    title,
+    description,
+    content = 'tasks',
+    content_rowid = 'seq',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO tasks_fts(tasks_fts) VALUES ('rebuild');
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+    INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+    INSERT INTO tasks_fts(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+DROP TABLE tasks_fts;
+
+CREATE TABLE tasks_old(
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    due_until TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    priority INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    completed_at TIMESTAMP,
+    parent_id TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+    series_id TEXT REFERENCES task_series(id) ON DELETE SET NULL
+);
+
+INSERT INTO tasks_old(rowid, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id)
+SELECT seq, id, created_at, updated_at, due_until, title, description, priority, user_id, status, completed_at, parent_id, series_id
+FROM tasks;
+
+DROP TABLE tasks;
+ALTER TABLE tasks_old RENAME TO tasks;
+
+CREATE INDEX tasks_parent_id_idx ON tasks(parent_id);
+CREATE INDEX tasks_series_id_idx ON tasks(series_id);
+
+CREATE VIRTUAL TABLE tasks_fts USING fts5(
+    task_id UNINDEXED,
+    title,
+    description,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO tasks_fts(task_id, title, description)
+SELECT id, title, description FROM tasks;
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts(task_id, title, description) VALUES (new.id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    DELETE FROM tasks_fts WHERE task_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+    UPDATE tasks_fts SET title = new.title, description = new.description WHERE task_id = old.id;
+END;
+-- +goose StatementEnd
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
@@ -1,3 +0,0 @@
-#!/usr/bin/env bash
-
-goose sqlite3 ../../taskin.db $1
\ No newline at end of file
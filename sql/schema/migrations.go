@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var migrations embed.FS
+
+var ErrDatabaseNewer = errors.New("database schema is newer than this binary")
+
+// Migrator applies the migrations embedded in the binary, goose keeps track of the applied ones
+// in the goose_db_version table.
+type Migrator struct {
+	provider *goose.Provider
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{provider: provider}, nil
+}
+
+// Check fails with ErrDatabaseNewer when the database was migrated by a newer binary, running
+// against a schema it doesn't know could corrupt data.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, latest, err := m.Versions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, the latest migration is %d", ErrDatabaseNewer, current, latest)
+	}
+
+	return nil
+}
+
+// Versions returns the version the database is at and the latest embedded migration.
+func (m *Migrator) Versions(ctx context.Context) (current, latest int64, err error) {
+	return m.provider.GetVersions(ctx)
+}
+
+// HasPending reports whether some of the embedded migrations weren't applied yet.
+func (m *Migrator) HasPending(ctx context.Context) (bool, error) {
+	return m.provider.HasPending(ctx)
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	if err := m.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	if err := m.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.provider.Down(ctx)
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
@@ -0,0 +1,197 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/sql/schema"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func setupMigrator(t *testing.T) (*sql.DB, *schema.Migrator) {
+	// migrations run with foreign keys on, like they do in the server
+	db, err := sql.Open("sqlite", config.Config{DBString: ":memory:"}.DSN())
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	migrator, err := schema.NewMigrator(db)
+	assert.NoError(t, err)
+
+	return db, migrator
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db, migrator := setupMigrator(t)
+
+	pending, err := migrator.HasPending(ctx)
+	assert.NoError(t, err)
+	assert.True(t, pending)
+
+	results, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, results)
+
+	pending, err = migrator.HasPending(ctx)
+	assert.NoError(t, err)
+	assert.False(t, pending)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(results))
+	for _, status := range statuses {
+		assert.False(t, status.AppliedAt.IsZero(), status.Source.Path)
+	}
+
+	// every migration can be rolled back and applied again
+	for range results {
+		_, err := migrator.Down(ctx)
+		assert.NoError(t, err)
+	}
+
+	var tables int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&tables)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, tables)
+
+	again, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, again, len(results))
+}
+
+func TestRefusesNewerDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, migrator := setupMigrator(t)
+
+	_, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO goose_db_version(version_id, is_applied) VALUES (99999, 1)")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, migrator.Check(ctx), schema.ErrDatabaseNewer)
+
+	_, err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, schema.ErrDatabaseNewer)
+
+	_, err = migrator.Down(ctx)
+	assert.ErrorIs(t, err, schema.ErrDatabaseNewer)
+}
+
+// migrateDownTo rolls the database back until version is the latest applied migration.
+func migrateDownTo(t *testing.T, migrator *schema.Migrator, version int64) {
+	for {
+		current, _, err := migrator.Versions(context.Background())
+		assert.NoError(t, err)
+		if current <= version {
+			return
+		}
+
+		_, err = migrator.Down(context.Background())
+		assert.NoError(t, err)
+	}
+}
+
+func countRows(t *testing.T, db *sql.DB, query string, args ...any) int {
+	var count int
+	err := db.QueryRow(query, args...).Scan(&count)
+	assert.NoError(t, err)
+
+	return count
+}
+
+func TestSubtaskParentForeignKeyKeepsData(t *testing.T) {
+	ctx := context.Background()
+	db, migrator := setupMigrator(t)
+
+	_, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	migrateDownTo(t, migrator, 21)
+
+	_, err = db.Exec(`
+		INSERT INTO users(id, created_at, updated_at, email, username, hashed_password)
+		VALUES ('owner', '2030-01-01', '2030-01-01', 'owner@test.com', 'owner', 'hash'),
+		       ('friend', '2030-01-01', '2030-01-01', 'friend@test.com', 'friend', 'hash');
+		INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, user_id, parent_id)
+		VALUES ('root', '2030-01-01', '2030-01-01', '2030-01-02', 'water plants', '', 1, 'owner', NULL),
+		       ('child', '2030-01-01', '2030-01-01', '2030-01-02', 'buy fertilizer', '', 1, 'owner', 'root'),
+		       ('orphan', '2030-01-01', '2030-01-01', '2030-01-02', 'call plumber', '', 1, 'owner', 'deleted');
+		INSERT INTO task_shares(task_id, user_id, created_at) VALUES ('root', 'friend', '2030-01-01');
+		INSERT INTO tags(id, created_at, updated_at, name, user_id) VALUES ('home', '2030-01-01', '2030-01-01', 'home', 'owner');
+		INSERT INTO task_tags(task_id, tag_id) VALUES ('root', 'home');
+	`)
+	assert.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+
+	// rebuilding tasks didn't cascade into the tables referencing it
+	assert.Equal(t, 3, countRows(t, db, "SELECT COUNT(*) FROM tasks"))
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM task_shares"))
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM task_tags"))
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'fertilizer'"))
+
+	// subtasks of tasks deleted before became top level ones
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tasks WHERE id = 'orphan' AND parent_id IS NULL"))
+
+	_, err = db.Exec("DELETE FROM tasks WHERE id = 'root'")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tasks"))
+	assert.Equal(t, 0, countRows(t, db, "SELECT COUNT(*) FROM task_shares"))
+	assert.Equal(t, 0, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'fertilizer'"))
+}
+
+func TestTasksFTSFollowsTasks(t *testing.T) {
+	ctx := context.Background()
+	db, migrator := setupMigrator(t)
+
+	_, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	migrateDownTo(t, migrator, 22)
+
+	_, err = db.Exec(`
+		INSERT INTO users(id, created_at, updated_at, email, username, hashed_password)
+		VALUES ('owner', '2030-01-01', '2030-01-01', 'owner@test.com', 'owner', 'hash');
+		INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, user_id)
+		VALUES ('plants', '2030-01-01', '2030-01-01', '2030-01-02', 'water plants', '', 1, 'owner'),
+		       ('plumber', '2030-01-01', '2030-01-01', '2030-01-02', 'call plumber', 'kitchen sink', 1, 'owner');
+	`)
+	assert.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+
+	// tasks from before the migration were indexed under their new seq
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'plants'"))
+
+	_, err = db.Exec(`
+		INSERT INTO tasks(id, created_at, updated_at, due_until, title, description, priority, user_id)
+		VALUES ('bike', '2030-01-01', '2030-01-01', '2030-01-02', 'fix bike', '', 1, 'owner');
+		UPDATE tasks SET title = 'call electrician' WHERE id = 'plumber';
+		DELETE FROM tasks WHERE id = 'plants';
+	`)
+	assert.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO tasks_fts(tasks_fts, rank) VALUES ('integrity-check', 1)")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'plants'"))
+	assert.Equal(t, 0, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'plumber'"))
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'electrician sink'"))
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tasks_fts WHERE tasks_fts MATCH 'bike'"))
+
+	// seq is the rowid of tasks, a VACUUM keeps it and the index keeps pointing at the same tasks
+	_, err = db.Exec("VACUUM")
+	assert.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO tasks_fts(tasks_fts, rank) VALUES ('integrity-check', 1)")
+	assert.NoError(t, err)
+
+	var id string
+	err = db.QueryRow("SELECT tasks.id FROM tasks_fts JOIN tasks ON tasks.seq = tasks_fts.rowid WHERE tasks_fts MATCH 'bike'").Scan(&id)
+	assert.NoError(t, err)
+	assert.Equal(t, "bike", id)
+}
@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/magicznykacpur/taskin-backend/config"
+	"github.com/magicznykacpur/taskin-backend/internal/database"
+	"github.com/magicznykacpur/taskin-backend/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// setupRecorder installs a tracer provider that keeps the finished spans in memory.
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Setup(context.Background(), config.Tracing{Exporter: config.TracingNone}, "test")
+	assert.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), config.Tracing{Exporter: config.TracingNone}, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = tracing.Setup(context.Background(), config.Tracing{Exporter: "jaeger"}, "test")
+	assert.ErrorContains(t, err, "unknown tracing exporter")
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	var handlerSpan trace.SpanContext
+	e := echo.New()
+	e.Use(tracing.Middleware())
+	e.GET("/api/tasks/:id", func(c echo.Context) error {
+		handlerSpan = trace.SpanContextFromContext(c.Request().Context())
+		return c.String(http.StatusOK, "task")
+	})
+	e.GET("/api/broken", func(c echo.Context) error {
+		return errors.New("broken")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/broken", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	task := spans[0]
+	assert.Equal(t, "GET /api/tasks/:id", task.Name())
+	assert.Equal(t, trace.SpanKindServer, task.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", task.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", task.Parent().SpanID().String())
+	assert.True(t, task.Parent().IsRemote())
+	assert.Equal(t, task.SpanContext(), handlerSpan)
+	assert.Equal(t, "/api/tasks/:id", attributeValue(task, "http.route").AsString())
+	assert.Equal(t, "/api/tasks/1", attributeValue(task, "url.path").AsString())
+	assert.Equal(t, int64(http.StatusOK), attributeValue(task, "http.response.status_code").AsInt64())
+	assert.Equal(t, codes.Unset, task.Status().Code)
+
+	broken := spans[1]
+	assert.Equal(t, "GET /api/broken", broken.Name())
+	assert.False(t, broken.Parent().IsValid())
+	assert.Equal(t, int64(http.StatusInternalServerError), attributeValue(broken, "http.response.status_code").AsInt64())
+	assert.Equal(t, codes.Error, broken.Status().Code)
+}
+
+func TestWrapDB(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE sessions(id TEXT, revoked_at TIMESTAMP, expires_at TIMESTAMP NOT NULL)`)
+	assert.NoError(t, err)
+
+	queries := database.New(tracing.WrapDB(db))
+
+	// queries outside of a trace don't start one
+	_, err = queries.CountActiveSessions(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := tracing.Start(context.Background(), "request")
+	_, err = queries.CountActiveSessions(ctx, time.Now())
+	assert.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	query := spans[0]
+	assert.Equal(t, "CountActiveSessions", query.Name())
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, "sqlite", attributeValue(query, "db.system").AsString())
+	assert.Equal(t, "CountActiveSessions", attributeValue(query, "db.operation.name").AsString())
+	assert.Contains(t, attributeValue(query, "db.query.text").AsString(), "FROM sessions")
+}